@@ -0,0 +1,100 @@
+package apikey
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/puras/mog/middleware"
+	"github.com/puras/mog/web"
+)
+
+// HeaderName 携带 API Key 的请求头，也可以用 Authorization: Bearer mk.... 传递
+const HeaderName = "X-API-Key"
+
+// Parser 优先按 API Key 认证，请求未携带 API Key 时交给 next（一般为 JWT 的 Parse），
+// 可作为 middleware.AuthConfig.Parse
+func Parser(service *Service, next func(*gin.Context) (*middleware.AuthInfo, error)) func(*gin.Context) (*middleware.AuthInfo, error) {
+	return func(c *gin.Context) (*middleware.AuthInfo, error) {
+		raw := c.GetHeader(HeaderName)
+		if token := web.GetToken(c); raw == "" && strings.HasPrefix(token, KeyPrefix) {
+			raw = token
+		}
+		if raw == "" {
+			if next == nil {
+				return nil, ErrInvalidKey
+			}
+			return next(c)
+		}
+
+		key, err := service.Authenticate(c.Request.Context(), raw)
+		if err != nil {
+			return nil, err
+		}
+		return &middleware.AuthInfo{UserId: key.Subject, Permissions: key.Scopes}, nil
+	}
+}
+
+// Created 创建或轮换后的 API Key，Key 为只返回一次的明文
+type Created struct {
+	*APIKey
+	Key string `json:"key"`
+}
+
+// Api API Key 的管理接口，需要由调用方通过中间件限制为管理员访问
+type Api struct {
+	Service *Service
+}
+
+func NewApi(service *Service) *Api {
+	return &Api{Service: service}
+}
+
+// Register 挂载 GET/POST {path}、POST {path}/:id/rotate、DELETE {path}/:id
+func (self *Api) Register(group gin.IRouter, path string, handlers ...gin.HandlerFunc) {
+	g := group.Group("/"+strings.Trim(path, "/"), handlers...)
+	g.GET("", self.List)
+	g.POST("", self.Create)
+	g.POST("/:"+web.PARAM_ID+"/rotate", self.Rotate)
+	g.DELETE("/:"+web.PARAM_ID, self.Revoke)
+}
+
+// List 列出 API Key，可按 subject 参数过滤
+func (self *Api) List(c *gin.Context) {
+	list, err := self.Service.List(c.Request.Context(), c.Query("subject"))
+	if err != nil {
+		web.ResError(c, err)
+		return
+	}
+	web.ResSuccess(c, list)
+}
+
+func (self *Api) Create(c *gin.Context) {
+	var form CreateForm
+	if err := web.ParseJSON(c, &form); err != nil {
+		web.ResError(c, err)
+		return
+	}
+	key, raw, err := self.Service.Create(c.Request.Context(), form)
+	if err != nil {
+		web.ResError(c, err)
+		return
+	}
+	web.ResSuccess(c, Created{APIKey: key, Key: raw})
+}
+
+func (self *Api) Rotate(c *gin.Context) {
+	key, raw, err := self.Service.Rotate(c.Request.Context(), c.Param(web.PARAM_ID))
+	if err != nil {
+		web.ResError(c, err)
+		return
+	}
+	web.ResSuccess(c, Created{APIKey: key, Key: raw})
+}
+
+func (self *Api) Revoke(c *gin.Context) {
+	if err := self.Service.Revoke(c.Request.Context(), c.Param(web.PARAM_ID)); err != nil {
+		web.ResError(c, err)
+		return
+	}
+	web.ResOk(c)
+}
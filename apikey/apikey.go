@@ -0,0 +1,207 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/puras/mog/crud"
+	"github.com/puras/mog/dbx"
+	"github.com/puras/mog/errors"
+	"github.com/puras/mog/i18n"
+	"github.com/puras/mog/model"
+	"gorm.io/gorm"
+)
+
+// KeyPrefix API Key 的固定前缀，用于与 JWT 等其他令牌区分。
+// "." 不在 base64url 字母表中，不透明令牌不会以它开头
+const KeyPrefix = "mk."
+
+// DefaultLastUsedInterval 两次记录最近使用时间的最小间隔，避免每次请求都写库
+const DefaultLastUsedInterval = time.Minute
+
+var ErrInvalidKey = errors.Unauthorized("invalid_api_key", "Invalid API key")
+
+// APIKey 机器客户端的访问凭证，只保存 Key 的 SHA-256，明文只在创建和轮换时返回一次
+type APIKey struct {
+	model.Model
+	Name       string     `json:"name" gorm:"size:64"`
+	Subject    string     `json:"subject" gorm:"size:64;index"` // 认证后的 UserId
+	Prefix     string     `json:"prefix" gorm:"size:16"`        // Key 的前几位，便于识别
+	Hash       string     `json:"-" gorm:"size:64;uniqueIndex"`
+	Scopes     []string   `json:"scopes" gorm:"serializer:json"` // 认证后作为 AuthInfo.Permissions
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+}
+
+func (APIKey) ResourceName() string {
+	return "api_key"
+}
+
+// Active 未吊销且未过期
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+type CreateForm struct {
+	Name      string     `json:"name"`
+	Subject   string     `json:"subject"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+func (f CreateForm) Validate() error {
+	if f.Name == "" || f.Subject == "" {
+		return errors.BadRequest("", "name and subject are required")
+	}
+	if f.ExpiresAt != nil && !f.ExpiresAt.After(time.Now()) {
+		return errors.BadRequest("", "expiresAt must be in the future")
+	}
+	return nil
+}
+
+// Service 管理和校验 API Key
+type Service struct {
+	DB               *gorm.DB
+	LastUsedInterval time.Duration // 默认 DefaultLastUsedInterval
+}
+
+func NewService(db *gorm.DB) *Service {
+	return &Service{DB: db}
+}
+
+// Create 创建 API Key，返回的明文 Key 只有这一次机会保存
+func (self *Service) Create(ctx context.Context, form CreateForm) (*APIKey, string, error) {
+	if err := form.Validate(); err != nil {
+		return nil, "", err
+	}
+	raw, err := generate()
+	if err != nil {
+		return nil, "", err
+	}
+	key := &APIKey{
+		Name:      form.Name,
+		Subject:   form.Subject,
+		Prefix:    raw[:len(KeyPrefix)+8],
+		Hash:      hash(raw),
+		Scopes:    form.Scopes,
+		ExpiresAt: form.ExpiresAt,
+	}
+	key.DefaultCreated()
+	if err := dbx.GetDB(ctx, self.DB).Create(key).Error; err != nil {
+		return nil, "", err
+	}
+	return key, raw, nil
+}
+
+// List 列出 API Key，subject 为空时列出全部
+func (self *Service) List(ctx context.Context, subject string) ([]*APIKey, error) {
+	db := dbx.GetDB(ctx, self.DB).Model(&APIKey{})
+	if subject != "" {
+		db = db.Where("subject = ?", subject)
+	}
+	var list []*APIKey
+	err := db.Order("created_at DESC").Find(&list).Error
+	return list, err
+}
+
+func (self *Service) Get(ctx context.Context, id string) (*APIKey, error) {
+	key := new(APIKey)
+	err := dbx.GetDB(ctx, self.DB).Where("id = ?", id).First(key).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, notFound(id)
+	}
+	return key, err
+}
+
+// Rotate 为 API Key 生成新的明文，旧 Key 立即失效，名称、范围、有效期不变
+func (self *Service) Rotate(ctx context.Context, id string) (*APIKey, string, error) {
+	key, err := self.Get(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if key.RevokedAt != nil {
+		return nil, "", errors.BadRequest("", "API key has been revoked")
+	}
+	raw, err := generate()
+	if err != nil {
+		return nil, "", err
+	}
+	key.Prefix, key.Hash = raw[:len(KeyPrefix)+8], hash(raw)
+	key.DefaultUpdated()
+	err = dbx.GetDB(ctx, self.DB).Model(key).Select("prefix", "hash", "updated_at").Updates(key).Error
+	if err != nil {
+		return nil, "", err
+	}
+	return key, raw, nil
+}
+
+// Revoke 吊销 API Key，保留记录以便审计
+func (self *Service) Revoke(ctx context.Context, id string) error {
+	key, err := self.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	return dbx.GetDB(ctx, self.DB).Model(key).
+		Updates(map[string]any{"revoked_at": now, "updated_at": now}).Error
+}
+
+// Authenticate 校验明文 Key，并按 LastUsedInterval 记录最近使用时间
+func (self *Service) Authenticate(ctx context.Context, raw string) (*APIKey, error) {
+	if !strings.HasPrefix(raw, KeyPrefix) {
+		return nil, ErrInvalidKey
+	}
+	key := new(APIKey)
+	err := dbx.GetDB(ctx, self.DB).Where("hash = ?", hash(raw)).First(key).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrInvalidKey
+	} else if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !key.Active(now) {
+		return nil, ErrInvalidKey
+	}
+
+	interval := self.LastUsedInterval
+	if interval <= 0 {
+		interval = DefaultLastUsedInterval
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= interval {
+		key.LastUsedAt = &now
+		err := dbx.GetDB(ctx, self.DB).Model(key).UpdateColumn("last_used_at", now).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// generate 生成 KeyPrefix 加 32 字节随机数的明文 Key
+func generate() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return KeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hash(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func notFound(id string) error {
+	err := errors.NotFound(crud.ErrNotFoundId, "")
+	resource := i18n.Phrase{Id: "resource.api_key", Default: "API Key"}
+	return i18n.WithParams(err, map[string]any{"resource": resource, "id": id})
+}
@@ -0,0 +1,139 @@
+package apikey
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/puras/mog/contextx"
+	"github.com/puras/mog/dbx"
+	"github.com/puras/mog/middleware"
+	"github.com/puras/mog/web"
+)
+
+func newService(t *testing.T) *Service {
+	db, err := dbx.NewDB(dbx.Config{DBType: "sqlite3", DSN: filepath.Join(t.TempDir(), "apikey.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&APIKey{}); err != nil {
+		t.Fatal(err)
+	}
+	return NewService(db)
+}
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	service := newService(t)
+
+	key, raw, err := service.Create(ctx, CreateForm{Name: "cron", Subject: "svc-cron", Scopes: []string{"report:read"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(raw, KeyPrefix) || key.Hash == raw || !strings.HasPrefix(raw, key.Prefix) {
+		t.Fatalf("unexpected key: %s %+v", raw, key)
+	}
+
+	got, err := service.Authenticate(ctx, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Subject != "svc-cron" || len(got.Scopes) != 1 || got.LastUsedAt == nil {
+		t.Fatalf("unexpected key: %+v", got)
+	}
+	if _, err := service.Authenticate(ctx, KeyPrefix+"unknown"); err != ErrInvalidKey {
+		t.Fatalf("expected ErrInvalidKey, got %v", err)
+	}
+
+	_, rotated, err := service.Rotate(ctx, key.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Authenticate(ctx, raw); err != ErrInvalidKey {
+		t.Fatalf("rotated key should be rejected, got %v", err)
+	}
+	if _, err := service.Authenticate(ctx, rotated); err != nil {
+		t.Fatalf("new key should be accepted: %v", err)
+	}
+
+	if err := service.Revoke(ctx, key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Authenticate(ctx, rotated); err != ErrInvalidKey {
+		t.Fatalf("revoked key should be rejected, got %v", err)
+	}
+
+	expired, _ := time.Parse(time.RFC3339, "2000-01-01T00:00:00Z")
+	if _, _, err := service.Create(ctx, CreateForm{Name: "old", Subject: "svc", ExpiresAt: &expired}); err == nil {
+		t.Fatal("expiry in the past should be rejected")
+	}
+}
+
+func TestParser(t *testing.T) {
+	service := newService(t)
+	_, raw, _ := service.Create(context.Background(), CreateForm{Name: "partner", Subject: "svc-partner", Scopes: []string{"order:read"}})
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	jwtParser := func(c *gin.Context) (*middleware.AuthInfo, error) {
+		return &middleware.AuthInfo{UserId: "jwt-" + web.GetToken(c)}, nil
+	}
+	e.Use(middleware.AuthWithConfig(middleware.AuthConfig{Parse: Parser(service, jwtParser)}))
+	e.GET("/orders", middleware.RequirePermissions("order:read"), func(c *gin.Context) {
+		web.ResSuccess(c, contextx.FromUserId(c.Request.Context()))
+	})
+	NewApi(service).Register(e, "/api-keys")
+
+	serve := func(method, path, body string, header ...string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		e.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := serve("GET", "/orders", "", HeaderName, raw); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "svc-partner") {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+	if w := serve("GET", "/orders", "", "Authorization", "Bearer "+raw); w.Code != http.StatusOK {
+		t.Fatalf("bearer api keys should be accepted: %d %s", w.Code, w.Body.String())
+	}
+	if w := serve("GET", "/orders", "", HeaderName, KeyPrefix+"bad"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d %s", w.Code, w.Body.String())
+	}
+	// 没有 API Key 时交给 JWT 解析，缺少权限返回 403；以 mk_ 开头的不透明令牌同样交给 JWT
+	for _, token := range []string{"jwt", "mk_opaque"} {
+		if w := serve("GET", "/orders", "", "Authorization", "Bearer "+token); w.Code != http.StatusForbidden {
+			t.Fatalf("expected 403, got %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	w := serve("POST", "/api-keys", `{"name":"job","subject":"svc-job","scopes":["order:read"]}`, HeaderName, raw)
+	var res struct {
+		Data Created `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || res.Data.Key == "" || res.Data.ID == "" {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), `"hash"`) {
+		t.Fatal("hash should not be exposed")
+	}
+	if w := serve("POST", "/api-keys/"+res.Data.ID+"/rotate", "", HeaderName, raw); w.Code != http.StatusOK {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+	if w := serve("DELETE", "/api-keys/"+res.Data.ID, "", HeaderName, raw); w.Code != http.StatusOK {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+	if w := serve("DELETE", "/api-keys/missing", "", HeaderName, raw); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "API Key不存在") {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+}
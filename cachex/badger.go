@@ -73,20 +73,34 @@ func (o *badgerCache) Get(ctx context.Context, ns, key string) (string, bool, er
 }
 
 func (o *badgerCache) GetAndDelete(ctx context.Context, ns, key string) (string, bool, error) {
-	value, ok, err := o.Get(ctx, ns, key)
-	if err != nil {
-		return "", false, err
-	} else if !ok {
-		return "", false, nil
-	}
-
-	err = o.db.Update(func(txn *badger.Txn) error {
-		return txn.Delete(o.strToBytes(o.getKey(ns, key)))
+	value := ""
+	ok := false
+	// 在同一事务中读取和删除，并发事务冲突时只有一个能提交
+	err := o.db.Update(func(txn *badger.Txn) error {
+		k := o.strToBytes(o.getKey(ns, key))
+		item, err := txn.Get(k)
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return nil
+			}
+			return err
+		}
+		val, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		if err := txn.Delete(k); err != nil {
+			return err
+		}
+		value, ok = o.bytesToStr(val), true
+		return nil
 	})
-	if err != nil {
+	if err == badger.ErrConflict {
+		return "", false, nil
+	} else if err != nil {
 		return "", false, err
 	}
-	return value, true, nil
+	return value, ok, nil
 }
 
 func (o *badgerCache) Exists(ctx context.Context, ns, key string) (bool, error) {
@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"github.com/patrickmn/go-cache"
 	"strings"
+	"sync"
 	"time"
 )
 
 type Cache interface {
 	Set(ctx context.Context, ns, key, value string, expiration ...time.Duration) error
 	Get(ctx context.Context, ns, key string) (string, bool, error)
+	// GetAndDelete 原子地读取并删除，并发调用时只有一个能取到值
 	GetAndDelete(ctx context.Context, ns, key string) (string, bool, error)
 	Exists(ctx context.Context, ns, key string) (bool, error)
 	Delete(ctx context.Context, ns, key string) error
@@ -54,6 +56,7 @@ func NewMemoryCache(cfg MemoryConfig, opts ...Option) Cache {
 type memCache struct {
 	opts  *options
 	cache *cache.Cache
+	mu    sync.Mutex // 保证 GetAndDelete 的原子性
 }
 
 func (o *memCache) getKey(ns, key string) string {
@@ -78,6 +81,8 @@ func (o *memCache) Get(ctx context.Context, ns, key string) (string, bool, error
 }
 
 func (o *memCache) GetAndDelete(ctx context.Context, ns, key string) (string, bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
 	val, ok, err := o.Get(ctx, ns, key)
 	if err != nil {
 		return "", false, err
@@ -51,6 +51,7 @@ func newRedisCache(cli redisClient, opts ...Option) Cache {
 type redisClient interface {
 	Set(ctx context.Context, key string, value any, expiration time.Duration) *redis.StatusCmd
 	Get(ctx context.Context, key string) *redis.StringCmd
+	GetDel(ctx context.Context, key string) *redis.StringCmd
 	Exists(ctx context.Context, keys ...string) *redis.IntCmd
 	Del(ctx context.Context, keys ...string) *redis.IntCmd
 	Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd
@@ -62,6 +63,9 @@ type redisCache struct {
 	cli  redisClient
 }
 
+// globEscaper 转义 SCAN MATCH 的通配符，避免 ns 中的 *、?、[ 匹配到其他命名空间
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
 func (o *redisCache) getKey(ns, key string) string {
 	return fmt.Sprintf("%s%s%s", ns, o.opts.Delimiter, key)
 }
@@ -87,17 +91,15 @@ func (o *redisCache) Get(ctx context.Context, ns, key string) (string, bool, err
 }
 
 func (o *redisCache) GetAndDelete(ctx context.Context, ns, key string) (string, bool, error) {
-	value, ok, err := o.Get(ctx, ns, key)
-	if err != nil {
-		return "", false, err
-	} else if !ok {
-		return "", false, nil
-	}
-	cmd := o.cli.Del(ctx, o.getKey(ns, key))
-	if err := cmd.Err(); err != nil && err != redis.Nil {
+	// GETDEL（Redis 6.2+）保证只有一个客户端能取到值
+	cmd := o.cli.GetDel(ctx, o.getKey(ns, key))
+	if err := cmd.Err(); err != nil {
+		if err == redis.Nil {
+			return "", false, nil
+		}
 		return "", false, err
 	}
-	return value, true, nil
+	return cmd.Val(), true, nil
 }
 
 func (o *redisCache) Exists(ctx context.Context, ns, key string) (bool, error) {
@@ -126,7 +128,7 @@ func (o *redisCache) Iterator(ctx context.Context, ns string, fn func(ctx contex
 	var cursor uint64 = 0
 LB_LOOP:
 	for {
-		cmd := o.cli.Scan(ctx, cursor, o.getKey(ns, "*"), 100)
+		cmd := o.cli.Scan(ctx, cursor, o.getKey(globEscaper.Replace(ns), "*"), 100)
 		if err := cmd.Err(); err != nil {
 			return err
 		}
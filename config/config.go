@@ -72,17 +72,20 @@ type Storage struct {
 		}
 	}
 	DataBase struct {
-		Enable       bool `default:"true"`
-		Debug        bool
-		Type         string `default:"sqlite3"` // sqlite3/mysql/postgres
-		DSN          string `default:"data/sqlite/106hz.db"`
-		MaxLifetime  int    `default:"86400"`
-		MaxIdleTime  int    `default:"3600"`
-		MaxOpenConns int    `default:"100"`
-		MaxIdleConns int    `default:"50"`
-		TablePrefix  string `default:""`
-		AutoMigrate  bool
-		Resolver     []struct {
+		Enable        bool `default:"true"`
+		Debug         bool
+		Type          string `default:"sqlite3"` // sqlite3/mysql/postgres
+		DSN           string `default:"data/sqlite/106hz.db"`
+		MaxLifetime   int    `default:"86400"`
+		MaxIdleTime   int    `default:"3600"`
+		MaxOpenConns  int    `default:"100"`
+		MaxIdleConns  int    `default:"50"`
+		TablePrefix   string `default:""`
+		AutoMigrate   bool
+		StickyPrimary int  // 写后粘滞主库窗口（秒），0 表示本次请求剩余时间
+		SlowThreshold int  `default:"200"` // 慢查询阈值（毫秒），0 关闭
+		RedactParams  bool // 日志中隐藏 SQL 参数
+		Resolver      []struct {
 			DBType   string   // sqlite3/mysql/postgres
 			Sources  []string // DSN
 			Replicas []string // DSN
@@ -176,10 +179,28 @@ type Middleware struct {
 	Auth struct {
 		Disable             bool
 		SkippedPathPrefixes []string
-		SigningMethod       string `default:"HS512"` // HS256/HS384/HS512
-		SigningKey          string `default:"cptbtptpbcptdtptp"`
-		Expired             int    `default:"86400"`
-		Store               struct {
+		Mode                string     `default:"jwt"` // jwt/opaque，opaque 签发不透明令牌，声明只保存在服务端
+		IntrospectClients   []struct { // 允许调用 /oauth2/introspect 令牌自省接口（RFC 7662）的服务，为空时不挂载
+			ClientId     string
+			ClientSecret string
+		}
+		SigningMethod  string     `default:"HS512"`             // HS256/384/512、RS256/384/512、PS256/384/512、ES256/384/512、EdDSA
+		SigningKey     string     `default:"cptbtptpbcptdtptp"` // HMAC 密钥
+		PrivateKeyFile string     // 非对称算法的 PEM 私钥文件
+		KeyId          string     // 令牌头部的 kid，非对称算法默认为公钥的 JWK Thumbprint
+		RetiredKeys    []struct { // 轮换后保留的旧密钥，只用于验证旧令牌
+			KeyId         string
+			SigningMethod string
+			SigningKey    string // HMAC 密钥
+			PublicKeyFile string // 非对称算法的 PEM 公钥文件
+		}
+		Expired        int      `default:"86400"`
+		RefreshExpired int      `default:"604800"` // 刷新令牌有效期（秒）
+		Issuer         string   // 令牌的 iss
+		Audience       []string // 令牌的 aud
+		Leeway         int      // 校验有效期时允许的时钟偏差（秒）
+		LegacyUntil    string   // RFC3339 时间，此前仍接受不带 iss/aud 的旧令牌
+		Store          struct {
 			Type      string `default:"badger"` // badger/redis
 			Delimiter string `default:":"`      // delimiter for key
 			Badger    struct {
@@ -2,6 +2,8 @@ package contextx
 
 import (
 	"context"
+	"sync/atomic"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -14,6 +16,15 @@ type (
 	roleCtx      struct{}
 	userTokenCtx struct{}
 	userCacheCtx struct{}
+
+	primaryCtx       struct{}
+	replicaCtx       struct{}
+	stickyPrimaryCtx struct{}
+	queryStatsCtx    struct{}
+	tenantIdCtx      struct{}
+	ignoreTenantCtx  struct{}
+	ifMatchCtx       struct{}
+	permissionsCtx   struct{}
 )
 
 func NewTraceId(ctx context.Context, traceId string) context.Context {
@@ -73,6 +84,54 @@ func FromRole(ctx context.Context) string {
 	return ""
 }
 
+func NewTenantId(ctx context.Context, tenantId string) context.Context {
+	return context.WithValue(ctx, tenantIdCtx{}, tenantId)
+}
+
+func FromTenantId(ctx context.Context) string {
+	v := ctx.Value(tenantIdCtx{})
+	if v != nil {
+		return v.(string)
+	}
+	return ""
+}
+
+// NewPermissions 记录当前用户的权限，一般来自令牌的自定义声明
+func NewPermissions(ctx context.Context, permissions []string) context.Context {
+	return context.WithValue(ctx, permissionsCtx{}, permissions)
+}
+
+func FromPermissions(ctx context.Context) []string {
+	v := ctx.Value(permissionsCtx{})
+	if v != nil {
+		return v.([]string)
+	}
+	return nil
+}
+
+// NewIgnoreTenant 显式跳过租户隔离，仅用于管理端等需要跨租户访问的操作。
+func NewIgnoreTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ignoreTenantCtx{}, true)
+}
+
+func FromIgnoreTenant(ctx context.Context) bool {
+	v := ctx.Value(ignoreTenantCtx{})
+	return v != nil && v.(bool)
+}
+
+// NewIfMatch 记录客户端期望的数据版本（乐观锁），一般来自 If-Match 请求头
+func NewIfMatch(ctx context.Context, version int64) context.Context {
+	return context.WithValue(ctx, ifMatchCtx{}, version)
+}
+
+func FromIfMatch(ctx context.Context) (int64, bool) {
+	v := ctx.Value(ifMatchCtx{})
+	if v != nil {
+		return v.(int64), true
+	}
+	return 0, false
+}
+
 func NewUserToken(ctx context.Context, userToken string) context.Context {
 	return context.WithValue(ctx, userTokenCtx{}, userToken)
 }
@@ -84,3 +143,89 @@ func FromUserToken(ctx context.Context) string {
 	}
 	return ""
 }
+
+// NewPrimary 强制后续查询走主库（写库），用于写后立即读等场景。
+func NewPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryCtx{}, true)
+}
+
+func FromPrimary(ctx context.Context) bool {
+	v := ctx.Value(primaryCtx{})
+	return v != nil && v.(bool)
+}
+
+// NewReplica 指定后续查询走从库（读库），主库提示 NewPrimary 优先级更高。
+func NewReplica(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replicaCtx{}, true)
+}
+
+func FromReplica(ctx context.Context) bool {
+	v := ctx.Value(replicaCtx{})
+	return v != nil && v.(bool)
+}
+
+// StickyPrimary 记录一次请求内最近的写库时间。
+// 写入后 Window 时间内的读取都会被路由到主库，以保证读己之写；
+// Window <= 0 表示写入后本次请求剩余的读取全部走主库。
+type StickyPrimary struct {
+	Window    time.Duration
+	lastWrite atomic.Int64
+}
+
+// MarkWrite 记录一次写库。
+func (s *StickyPrimary) MarkWrite() {
+	s.lastWrite.Store(time.Now().UnixNano())
+}
+
+// Active 判断当前是否处于写后粘滞主库窗口内。
+func (s *StickyPrimary) Active() bool {
+	last := s.lastWrite.Load()
+	if last == 0 {
+		return false
+	}
+	return s.Window <= 0 || time.Since(time.Unix(0, last)) < s.Window
+}
+
+func NewStickyPrimary(ctx context.Context, window time.Duration) context.Context {
+	return context.WithValue(ctx, stickyPrimaryCtx{}, &StickyPrimary{Window: window})
+}
+
+func FromStickyPrimary(ctx context.Context) (*StickyPrimary, bool) {
+	v := ctx.Value(stickyPrimaryCtx{})
+	if v != nil {
+		return v.(*StickyPrimary), true
+	}
+	return nil, false
+}
+
+// QueryStats 统计一次请求内执行的 SQL 次数与累计耗时。
+type QueryStats struct {
+	count atomic.Int64
+	cost  atomic.Int64
+}
+
+// Add 记录一次 SQL 执行。
+func (s *QueryStats) Add(elapsed time.Duration) {
+	s.count.Add(1)
+	s.cost.Add(int64(elapsed))
+}
+
+func (s *QueryStats) Count() int64 {
+	return s.count.Load()
+}
+
+func (s *QueryStats) Cost() time.Duration {
+	return time.Duration(s.cost.Load())
+}
+
+func NewQueryStats(ctx context.Context) context.Context {
+	return context.WithValue(ctx, queryStatsCtx{}, &QueryStats{})
+}
+
+func FromQueryStats(ctx context.Context) (*QueryStats, bool) {
+	v := ctx.Value(queryStatsCtx{})
+	if v != nil {
+		return v.(*QueryStats), true
+	}
+	return nil, false
+}
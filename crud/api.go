@@ -1,21 +1,35 @@
 package crud
 
 import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/puras/mog/contextx"
+	"github.com/puras/mog/errors"
+	"github.com/puras/mog/logger"
 	"github.com/puras/mog/model"
+	"github.com/puras/mog/openapi"
 	"github.com/puras/mog/web"
+	"go.uber.org/zap"
 )
 
 type ICrudApi[T model.IModel, D QueryParams, F model.IForm[T]] interface {
-	Query(c *gin.Context)
-	Get(c *gin.Context)
-	Create(c *gin.Context)
-	Update(c *gin.Context)
-	Delete(c *gin.Context)
+	IRestApi
 }
 
 type CrudApi[T model.IModel, D QueryParams, F model.IForm[T]] struct {
-	Biz ICrudBiz[T, F]
+	Biz           ICrudBiz[T, F]
+	ExportName    string // 导出文件名前缀，默认 export
+	MaxImportSize int64  // 导入文件最大字节数，默认 DefaultMaxImportSize
 }
 
 func NewApi[T model.IModel, D QueryParams, F model.IForm[T]](
@@ -42,6 +56,124 @@ func (self *CrudApi[T, D, F]) Query(c *gin.Context) {
 	web.ResPage(c, ret)
 }
 
+// Export 不分页地导出 Query 条件下的数据，format 参数为 csv（默认）或 xlsx，
+// 表头取自模型字段的 export tag
+func (self *CrudApi[T, D, F]) Export(c *gin.Context) {
+	ctx := c.Request.Context()
+	var params D
+	if err := web.ParseQuery(c, &params); err != nil {
+		web.ResError(c, err)
+		return
+	}
+	format := c.DefaultQuery("format", FormatCSV)
+	contentType, ok := ExportContentTypes[format]
+	if !ok {
+		web.ResError(c, errors.BadRequest("", "Unsupported export format: %s", format))
+		return
+	}
+
+	name := self.ExportName
+	if name == "" {
+		name = "export"
+	}
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102150405"), format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+
+	rw, err := NewRowWriter(format, c.Writer)
+	if err == nil {
+		defer rw.Close()
+		columns := ColumnsOf(reflect.TypeFor[T]())
+		if err = rw.WriteRow(columns.Headers()); err == nil {
+			err = self.Biz.Export(ctx, params, func(items []*T) error {
+				for _, item := range items {
+					if err := rw.WriteRow(columns.Values(item)); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+		if err == nil {
+			err = rw.Flush()
+		}
+	}
+	if err != nil {
+		if c.Writer.Written() {
+			// 已经开始输出文件，只能中断并记录错误
+			logger.From(ctx).Error("Failed to export", zap.Error(err))
+			c.Abort()
+			return
+		}
+		c.Writer.Header().Del("Content-Disposition")
+		web.ResError(c, err)
+		return
+	}
+	c.Status(http.StatusOK)
+	c.Abort()
+}
+
+// Import 导入 multipart 表单 file 字段上传的 CSV/XLSX 文件，格式取 format 参数或文件扩展名，
+// mode 参数为 all（默认，任一行失败都不导入）或 valid（只导入校验通过的行）。
+// 没有导入任何数据且存在行错误时返回 400，data 均为 ImportResult
+func (self *CrudApi[T, D, F]) Import(c *gin.Context) {
+	ctx := c.Request.Context()
+	maxSize := self.MaxImportSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxImportSize
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if stderrors.As(err, &tooLarge) {
+			web.ResError(c, errors.RequestEntityTooLarge("", "Import file exceeds %d bytes", maxSize))
+			return
+		}
+		web.ResError(c, errors.BadRequest("", "Failed to read import file: %s", err.Error()))
+		return
+	}
+	format := c.Query("format")
+	if format == "" {
+		format = c.PostForm("format")
+	}
+	if format == "" {
+		format = strings.ToLower(strings.TrimPrefix(filepath.Ext(fh.Filename), "."))
+	}
+	mode := ImportMode(c.Query("mode"))
+	if mode == "" {
+		mode = ImportMode(c.PostForm("mode"))
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		web.ResError(c, errors.WithStack(err))
+		return
+	}
+	defer f.Close()
+	maxRows := DefaultMaxImportRows
+	if b, ok := self.Biz.(interface{ maxImportRows() int }); ok {
+		maxRows = b.maxImportRows()
+	}
+	rows, err := ReadRows(format, f, maxRows, maxSize)
+	if err != nil {
+		web.ResError(c, err)
+		return
+	}
+
+	result, err := self.Biz.Import(ctx, rows, mode)
+	if err != nil {
+		web.ResError(c, err)
+		return
+	}
+	result.Localize(web.Language(c))
+	if result.Created == 0 && len(result.Errors) > 0 {
+		web.ResJson(c, http.StatusBadRequest, web.NewResponseResult(strconv.Itoa(http.StatusBadRequest), "Import failed", result))
+		return
+	}
+	web.ResSuccess(c, result)
+}
+
 func (self *CrudApi[T, D, F]) Get(c *gin.Context) {
 	ctx := c.Request.Context()
 	id := c.Param(web.PARAM_ID)
@@ -50,6 +182,7 @@ func (self *CrudApi[T, D, F]) Get(c *gin.Context) {
 		web.ResError(c, err)
 		return
 	}
+	setETag(c, item)
 	web.ResSuccess(c, item)
 }
 
@@ -69,12 +202,20 @@ func (self *CrudApi[T, D, F]) Create(c *gin.Context) {
 		web.ResError(c, err)
 		return
 	}
+	setETag(c, ret)
 	web.ResSuccess(c, ret)
 }
 
+// Update 支持 If-Match 请求头（取值为 Get 返回的 ETag），版本不一致时返回 412，成功时返回新的 ETag
 func (self *CrudApi[T, D, F]) Update(c *gin.Context) {
 	ctx := c.Request.Context()
 	id := c.Param(web.PARAM_ID)
+	if version, ok, err := parseIfMatch(c); err != nil {
+		web.ResError(c, err)
+		return
+	} else if ok {
+		ctx = contextx.NewIfMatch(ctx, version)
+	}
 	item := new(F)
 	if err := web.ParseJSON(c, item); err != nil {
 		web.ResError(c, err)
@@ -84,14 +225,41 @@ func (self *CrudApi[T, D, F]) Update(c *gin.Context) {
 		return
 	}
 
-	err := self.Biz.Update(ctx, id, *item)
+	ret, err := self.Biz.Update(ctx, id, *item)
 	if err != nil {
 		web.ResError(c, err)
 		return
 	}
+	setETag(c, ret)
 	web.ResOk(c)
 }
 
+// Patch 局部更新，Content-Type 为 application/json-patch+json 时按 JSON Patch 处理，
+// 其余按 JSON Merge Patch 处理；同样支持 If-Match
+func (self *CrudApi[T, D, F]) Patch(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param(web.PARAM_ID)
+	if version, ok, err := parseIfMatch(c); err != nil {
+		web.ResError(c, err)
+		return
+	} else if ok {
+		ctx = contextx.NewIfMatch(ctx, version)
+	}
+	data, err := c.GetRawData()
+	if err != nil {
+		web.ResError(c, errors.BadRequest("", "Failed to read body: %s", err.Error()))
+		return
+	}
+
+	ret, err := self.Biz.Patch(ctx, id, Patch{Type: c.ContentType(), Data: data})
+	if err != nil {
+		web.ResError(c, err)
+		return
+	}
+	setETag(c, ret)
+	web.ResSuccess(c, ret)
+}
+
 func (self *CrudApi[T, D, F]) Delete(c *gin.Context) {
 	ctx := c.Request.Context()
 	id := c.Param(web.PARAM_ID)
@@ -102,3 +270,124 @@ func (self *CrudApi[T, D, F]) Delete(c *gin.Context) {
 	}
 	web.ResOk(c)
 }
+
+// BatchCreate 请求体为表单数组
+func (self *CrudApi[T, D, F]) BatchCreate(c *gin.Context) {
+	ctx := c.Request.Context()
+	var items []F
+	if err := web.ParseJSON(c, &items); err != nil {
+		web.ResError(c, err)
+		return
+	}
+
+	ret, err := self.Biz.BatchCreate(ctx, items)
+	if err != nil {
+		resError(c, err)
+		return
+	}
+	web.ResSuccess(c, ret)
+}
+
+// BatchUpdate 请求体为 [{"id": "...", "data": {...}}]
+func (self *CrudApi[T, D, F]) BatchUpdate(c *gin.Context) {
+	ctx := c.Request.Context()
+	var items []BatchUpdateItem[F]
+	if err := web.ParseJSON(c, &items); err != nil {
+		web.ResError(c, err)
+		return
+	}
+
+	if err := self.Biz.BatchUpdate(ctx, items); err != nil {
+		resError(c, err)
+		return
+	}
+	web.ResOk(c)
+}
+
+// BatchDelete 请求体为 {"ids": [...]}
+func (self *CrudApi[T, D, F]) BatchDelete(c *gin.Context) {
+	ctx := c.Request.Context()
+	var params BatchDeleteParams
+	if err := web.ParseJSON(c, &params); err != nil {
+		web.ResError(c, err)
+		return
+	}
+
+	if err := self.Biz.BatchDelete(ctx, params.Ids); err != nil {
+		resError(c, err)
+		return
+	}
+	web.ResOk(c)
+}
+
+// resError BatchError 以第一条错误的状态码响应，并在 data 中返回逐条错误
+func resError(c *gin.Context, err error) {
+	var batchErr *BatchError
+	if !stderrors.As(err, &batchErr) {
+		web.ResError(c, err)
+		return
+	}
+	code := int(batchErr.Items[0].Code)
+	web.ResJson(c, code, web.NewResponseResult(strconv.Itoa(code), "Batch operation failed", batchErr.Localize(web.Language(c))))
+}
+
+// describeRoute 根据 T/D/F 描述 Register 挂载的路由，用于生成 OpenAPI 文档
+func (self *CrudApi[T, D, F]) describeRoute(name string, r *openapi.Route) {
+	var (
+		item   T
+		params D
+		form   F
+	)
+	etag := map[string]string{"ETag": "数据版本，模型实现 model.IVersioned 时返回"}
+	ifMatch := map[string]string{"If-Match": "期望的数据版本（ETag），不一致时返回 412"}
+	switch name {
+	case RouteQuery:
+		r.Summary, r.Query, r.Response, r.Page = "分页查询", params, item, true
+	case RouteGet:
+		r.Summary, r.Response, r.ResHeaders = "查询详情", item, etag
+	case RouteCreate:
+		r.Summary, r.Body, r.Response, r.ResHeaders = "创建", form, item, etag
+	case RouteUpdate:
+		r.Summary, r.Body, r.Headers, r.ResHeaders = "更新", form, ifMatch, etag
+	case RoutePatch:
+		r.Summary, r.Body, r.Response, r.Headers, r.ResHeaders = "局部更新", json.RawMessage{}, item, ifMatch, etag
+		r.Description = "JSON Merge Patch（RFC 7396）或 JSON Patch（RFC 6902）"
+		r.BodyTypes = []string{MergePatchType, JSONPatchType}
+	case RouteDelete:
+		r.Summary = "删除"
+	case RouteBatchCreate:
+		r.Summary, r.Body, r.Response = "批量创建", []F{}, []*T{}
+	case RouteBatchUpdate:
+		r.Summary, r.Body = "批量更新", []BatchUpdateItem[F]{}
+	case RouteBatchDelete:
+		r.Summary, r.Body = "批量删除", BatchDeleteParams{}
+	case RouteExport:
+		r.Summary, r.Query = "导出", params
+		r.Description = "format 参数为 csv（默认）或 xlsx"
+		r.Produces = []string{ExportContentTypes[FormatCSV], ExportContentTypes[FormatXLSX]}
+	case RouteImport:
+		r.Summary, r.Body, r.Response = "导入", ImportFile{}, ImportResult{}
+		r.Description = "按表头映射到表单字段并逐行校验，没有导入任何数据且存在行错误时返回 400"
+		r.BodyTypes = []string{"multipart/form-data"}
+	}
+}
+
+// setETag 模型实现 model.IVersioned 时以版本号作为 ETag
+func setETag(c *gin.Context, item any) {
+	if versioned, ok := item.(model.IVersioned); ok {
+		c.Header("ETag", strconv.Quote(strconv.FormatInt(versioned.GetVersion(), 10)))
+	}
+}
+
+func parseIfMatch(c *gin.Context) (int64, bool, error) {
+	v := strings.TrimSpace(c.GetHeader("If-Match"))
+	if v == "" || v == "*" {
+		return 0, false, nil
+	}
+	v = strings.Trim(strings.TrimPrefix(v, "W/"), `"`)
+	version, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, false, errors.BadRequest("", "Invalid If-Match header: %s", c.GetHeader("If-Match"))
+	}
+	return version, true, nil
+}
@@ -2,19 +2,29 @@ package crud
 
 import (
 	"context"
+	"encoding/json"
+	stderrors "errors"
 
+	"github.com/puras/mog/contextx"
 	"github.com/puras/mog/dbx"
 	"github.com/puras/mog/errors"
+	"github.com/puras/mog/i18n"
 	"github.com/puras/mog/model"
 	"github.com/puras/mog/web"
 )
 
 type ICrudBiz[T model.IModel, V model.IForm[T]] interface {
 	Query(ctx context.Context, params QueryParams) (*web.PageResult, error)
+	Export(ctx context.Context, params QueryParams, fn func(items []*T) error) error
 	Get(ctx context.Context, id string) (*T, error)
 	Create(ctx context.Context, item V) (*T, error)
-	Update(ctx context.Context, id string, item V) error
+	Update(ctx context.Context, id string, item V) (*T, error)
+	Patch(ctx context.Context, id string, patch Patch) (*T, error)
 	Delete(ctx context.Context, id string) error
+	BatchCreate(ctx context.Context, items []V) ([]*T, error)
+	BatchUpdate(ctx context.Context, items []BatchUpdateItem[V]) error
+	BatchDelete(ctx context.Context, ids []string) error
+	Import(ctx context.Context, rows [][]string, mode ImportMode) (*ImportResult, error)
 }
 
 type UpdateQueryOptionsFunc func(ctx context.Context) (dbx.QueryOptions, error)
@@ -23,6 +33,11 @@ type CrudBiz[T model.IModel, V model.IForm[T]] struct {
 	Trans                  *dbx.Trans
 	Repo                   ICrudRepo[T]
 	UpdateQueryOptionsFunc UpdateQueryOptionsFunc
+	MaxBatchSize           int    // 批量操作最大条数，默认 DefaultMaxBatchSize
+	ExportBatchSize        int    // 导出时每批查询的行数，默认 DefaultExportBatchSize
+	MaxImportRows          int    // 单次导入最大数据行数，默认 DefaultMaxImportRows
+	ResourceName           string // 资源名称，用于错误消息，默认取模型实现的 model.IResource
+	Hooks                  Hooks[T]
 }
 
 func NewBiz[T model.IModel, V model.IForm[T]](
@@ -59,17 +74,55 @@ func (self *CrudBiz[T, V]) Query(ctx context.Context, params QueryParams) (*web.
 	return web.FromPaginationResult(ret), nil
 }
 
+// Export 不分页地导出 Query 条件下的全部数据，分批交给 fn 处理
+func (self *CrudBiz[T, V]) Export(ctx context.Context, params QueryParams, fn func(items []*T) error) error {
+	var queryOptions dbx.QueryOptions
+	if self.UpdateQueryOptionsFunc != nil {
+		opts, err := self.UpdateQueryOptionsFunc(ctx)
+		if err != nil {
+			return err
+		}
+		queryOptions = opts
+	}
+	batchSize := self.ExportBatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultExportBatchSize
+	}
+	return self.Repo.Export(ctx, params, batchSize, fn, queryOptions)
+}
+
 func (self *CrudBiz[T, V]) Get(ctx context.Context, id string) (*T, error) {
 	item, err := self.Repo.Get(ctx, id)
 	if err != nil {
 		return nil, err
 	} else if item == nil {
-		return nil, errors.NotFound(id, "用户不存在")
+		return nil, self.notFound(id)
 	}
 	return item, nil
 }
 
-func (self *CrudBiz[T, V]) Create(ctx context.Context, form V) (*T, error) {
+// resource 资源的展示名称，在 i18n 目录中以 resource.<name> 为键查找
+func (self *CrudBiz[T, V]) resource() i18n.Phrase {
+	name := self.ResourceName
+	if name == "" {
+		if r, ok := any(new(T)).(model.IResource); ok {
+			name = r.ResourceName()
+		}
+	}
+	if name == "" {
+		return i18n.Phrase{Id: "resource", Default: "数据"}
+	}
+	return i18n.Phrase{Id: "resource." + name, Default: name}
+}
+
+// notFound 数据不存在的错误，消息按 ErrNotFoundId 本地化
+func (self *CrudBiz[T, V]) notFound(id string) error {
+	err := errors.NotFound(ErrNotFoundId, "")
+	return i18n.WithParams(err, map[string]any{"resource": self.resource(), "id": id})
+}
+
+// newItem 由表单生成待创建的数据，并填充 ID、创建时间、初始版本号
+func (self *CrudBiz[T, V]) newItem(form V) (*T, error) {
 	item := new(T)
 	if err := form.FillTo(item); err != nil {
 		return nil, err
@@ -78,9 +131,22 @@ func (self *CrudBiz[T, V]) Create(ctx context.Context, form V) (*T, error) {
 	if defaulter, ok := any(item).(interface{ DefaultCreated() }); ok {
 		defaulter.DefaultCreated()
 	}
+	if versioned, ok := any(item).(model.IVersioned); ok && versioned.GetVersion() == 0 {
+		versioned.SetVersion(1)
+	}
+	return item, nil
+}
 
-	err := self.Trans.Exec(ctx, func(ctx context.Context) error {
-		return self.Repo.Create(ctx, item)
+func (self *CrudBiz[T, V]) Create(ctx context.Context, form V) (*T, error) {
+	item, err := self.newItem(form)
+	if err != nil {
+		return nil, err
+	}
+
+	err = self.Trans.Exec(ctx, func(ctx context.Context) error {
+		return self.Hooks.create(ctx, item, func() error {
+			return self.Repo.Create(ctx, item)
+		})
 	})
 	if err != nil {
 		return nil, err
@@ -88,25 +154,115 @@ func (self *CrudBiz[T, V]) Create(ctx context.Context, form V) (*T, error) {
 	return item, nil
 }
 
-func (self *CrudBiz[T, V]) Update(ctx context.Context, id string, form V) error {
+// Update 更新数据，模型实现 model.IVersioned 时以 contextx.FromIfMatch 中的版本
+// （未指定时为读取到的版本）做乐观锁校验
+func (self *CrudBiz[T, V]) Update(ctx context.Context, id string, form V) (*T, error) {
 	item, err := self.Repo.Get(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	} else if item == nil {
-		return errors.NotFound(id, "数据不存在")
+		return nil, self.notFound(id)
+	}
+
+	version, err := checkVersion(ctx, item)
+	if err != nil {
+		return nil, err
 	}
 
+	old := new(T)
+	*old = *item
 	if err := form.FillTo(item); err != nil {
-		return err
+		return nil, err
 	}
+	restoreVersion(item, version)
 
 	if defaulter, ok := any(item).(interface{ DefaultUpdated() }); ok {
 		defaulter.DefaultUpdated()
 	}
 
-	return self.Trans.Exec(ctx, func(ctx context.Context) error {
-		return self.Repo.Update(ctx, id, item)
+	err = self.Trans.Exec(ctx, func(ctx context.Context) error {
+		return self.Hooks.update(ctx, old, item, func() error {
+			return self.Repo.Update(ctx, id, item)
+		})
 	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Patch 局部更新：将补丁应用到数据的 JSON 表示上，解析为表单并校验后只更新变化的字段，
+// 因此表单的 json 字段名需与模型一致。乐观锁校验同 Update
+func (self *CrudBiz[T, V]) Patch(ctx context.Context, id string, patch Patch) (*T, error) {
+	old, err := self.Repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	} else if old == nil {
+		return nil, self.notFound(id)
+	}
+
+	version, err := checkVersion(ctx, old)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := json.Marshal(old)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	doc, err = patch.Apply(doc)
+	if err != nil {
+		return nil, err
+	}
+	form := new(V)
+	if err := json.Unmarshal(doc, form); err != nil {
+		return nil, errors.BadRequest("invalid_patch", "Failed to apply patch: %s", err.Error())
+	} else if err := (*form).Validate(); err != nil {
+		return nil, err
+	}
+
+	item := new(T)
+	*item = *old
+	if err := (*form).FillTo(item); err != nil {
+		return nil, err
+	}
+	restoreVersion(item, version)
+
+	err = self.Trans.Exec(ctx, func(ctx context.Context) error {
+		return self.Hooks.update(ctx, old, item, func() error {
+			return self.Repo.Patch(ctx, id, old, item)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// checkVersion 返回数据当前的版本号，与 contextx.FromIfMatch 不一致时返回 ErrPreconditionFailed
+func checkVersion(ctx context.Context, item any) (int64, error) {
+	if _, ok := item.(model.IVersioned); !ok {
+		return 0, nil
+	}
+	version := versionOf(item)
+	if expected, ok := contextx.FromIfMatch(ctx); ok && expected != version {
+		return 0, ErrPreconditionFailed
+	}
+	return version, nil
+}
+
+func versionOf(item any) int64 {
+	if versioned, ok := item.(model.IVersioned); ok {
+		return versioned.GetVersion()
+	}
+	return 0
+}
+
+// restoreVersion 版本号由服务端维护，不允许表单覆盖
+func restoreVersion(item any, version int64) {
+	if versioned, ok := item.(model.IVersioned); ok {
+		versioned.SetVersion(version)
+	}
 }
 
 func (self *CrudBiz[T, V]) Delete(ctx context.Context, id string) error {
@@ -114,10 +270,282 @@ func (self *CrudBiz[T, V]) Delete(ctx context.Context, id string) error {
 	if err != nil {
 		return err
 	} else if item == nil {
-		return errors.NotFound("", "数据不存在")
+		return self.notFound(id)
+	}
+
+	return self.Trans.Exec(ctx, func(ctx context.Context) error {
+		return self.Hooks.delete(ctx, item, func() error {
+			return self.Repo.Delete(ctx, id)
+		})
+	})
+}
+
+func (self *CrudBiz[T, V]) checkBatchSize(n int) error {
+	max := self.MaxBatchSize
+	if max <= 0 {
+		max = DefaultMaxBatchSize
+	}
+	if n == 0 {
+		return errors.BadRequest("", "Batch is empty")
+	} else if n > max {
+		err := errors.BadRequest("batch_too_large", "Batch size %d exceeds the limit %d", n, max)
+		return i18n.WithParams(err, map[string]any{"size": n, "max": max})
+	}
+	return nil
+}
+
+// BatchCreate 批量创建，任一数据校验失败时返回 BatchError，不创建任何数据
+func (self *CrudBiz[T, V]) BatchCreate(ctx context.Context, forms []V) ([]*T, error) {
+	if err := self.checkBatchSize(len(forms)); err != nil {
+		return nil, err
+	}
+
+	batchErr := &BatchError{}
+	items := make([]*T, len(forms))
+	for i, form := range forms {
+		if err := form.Validate(); err != nil {
+			batchErr.add(i, "", err)
+			continue
+		}
+		item, err := self.newItem(form)
+		if err != nil {
+			batchErr.add(i, "", err)
+			continue
+		}
+		items[i] = item
+	}
+	if err := batchErr.err(); err != nil {
+		return nil, err
+	}
+
+	err := self.Trans.Exec(ctx, func(ctx context.Context) error {
+		if self.Hooks.BeforeCreate != nil {
+			for i, item := range items {
+				if err := batchErr.collect(i, "", self.Hooks.BeforeCreate(ctx, item)); err != nil {
+					return err
+				}
+			}
+			if err := batchErr.err(); err != nil {
+				return err
+			}
+		}
+		if err := self.Repo.BatchCreate(ctx, items); err != nil {
+			return err
+		}
+		if self.Hooks.AfterCreate != nil {
+			for i, item := range items {
+				if err := batchErr.collect(i, (*item).GetID(), self.Hooks.AfterCreate(ctx, item)); err != nil {
+					return err
+				}
+			}
+		}
+		return batchErr.err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// BatchUpdate 批量更新，在同一事务中逐条更新，任一数据不存在、校验失败或版本冲突时
+// 返回 BatchError 并回滚
+func (self *CrudBiz[T, V]) BatchUpdate(ctx context.Context, updates []BatchUpdateItem[V]) error {
+	if err := self.checkBatchSize(len(updates)); err != nil {
+		return err
+	}
+
+	ids := make([]string, len(updates))
+	for i, u := range updates {
+		ids[i] = u.Id
+	}
+	list, err := self.Repo.GetByIds(ctx, ids)
+	if err != nil {
+		return err
+	}
+	loaded := make(map[string]*T, len(list))
+	for _, item := range list {
+		loaded[(*item).GetID()] = item
+	}
+
+	batchErr := &BatchError{}
+	olds := make([]*T, len(updates))
+	items := make([]*T, len(updates))
+	for i, u := range updates {
+		item, ok := loaded[u.Id]
+		if !ok {
+			batchErr.add(i, u.Id, self.notFound(u.Id))
+			continue
+		}
+		// 同一 id 出现多次时各自基于读取到的数据更新，后者会因版本冲突失败
+		copied := new(T)
+		*copied = *item
+		version := versionOf(copied)
+		if err := u.Data.Validate(); err != nil {
+			batchErr.add(i, u.Id, err)
+			continue
+		} else if err := u.Data.FillTo(copied); err != nil {
+			batchErr.add(i, u.Id, err)
+			continue
+		}
+		restoreVersion(copied, version)
+		if defaulter, ok := any(copied).(interface{ DefaultUpdated() }); ok {
+			defaulter.DefaultUpdated()
+		}
+		olds[i] = item
+		items[i] = copied
+	}
+	if err := batchErr.err(); err != nil {
+		return err
 	}
 
 	return self.Trans.Exec(ctx, func(ctx context.Context) error {
-		return self.Repo.Delete(ctx, id)
+		for i, item := range items {
+			id := updates[i].Id
+			err := self.Hooks.update(ctx, olds[i], item, func() error {
+				return self.Repo.Update(ctx, id, item)
+			})
+			if err := batchErr.collect(i, id, err); err != nil {
+				return err
+			}
+		}
+		return batchErr.err()
 	})
 }
+
+// BatchDelete 批量删除，任一数据不存在时返回 BatchError，不删除任何数据
+func (self *CrudBiz[T, V]) BatchDelete(ctx context.Context, ids []string) error {
+	if err := self.checkBatchSize(len(ids)); err != nil {
+		return err
+	}
+
+	list, err := self.Repo.GetByIds(ctx, ids)
+	if err != nil {
+		return err
+	}
+	loaded := make(map[string]*T, len(list))
+	for _, item := range list {
+		loaded[(*item).GetID()] = item
+	}
+	batchErr := &BatchError{}
+	items := make([]*T, len(ids))
+	for i, id := range ids {
+		if items[i] = loaded[id]; items[i] == nil {
+			batchErr.add(i, id, self.notFound(id))
+		}
+	}
+	if err := batchErr.err(); err != nil {
+		return err
+	}
+
+	return self.Trans.Exec(ctx, func(ctx context.Context) error {
+		if self.Hooks.BeforeDelete != nil {
+			for i, item := range items {
+				if err := batchErr.collect(i, ids[i], self.Hooks.BeforeDelete(ctx, item)); err != nil {
+					return err
+				}
+			}
+			if err := batchErr.err(); err != nil {
+				return err
+			}
+		}
+		if err := self.Repo.BatchDelete(ctx, ids); err != nil {
+			return err
+		}
+		if self.Hooks.AfterDelete != nil {
+			for i, item := range items {
+				if err := batchErr.collect(i, ids[i], self.Hooks.AfterDelete(ctx, item)); err != nil {
+					return err
+				}
+			}
+		}
+		return batchErr.err()
+	})
+}
+
+func (self *CrudBiz[T, V]) maxImportRows() int {
+	if self.MaxImportRows > 0 {
+		return self.MaxImportRows
+	}
+	return DefaultMaxImportRows
+}
+
+var errImportRejected = stderrors.New("import rejected")
+
+// Import 导入数据，rows 第一行为表头，按表头把各行映射为表单并校验。
+// ImportAllOrNone 模式下任一行失败都不导入任何数据，ImportValidOnly 模式下只导入校验通过的行；
+// 行级错误记录在 ImportResult.Errors 中，不作为 error 返回
+func (self *CrudBiz[T, V]) Import(ctx context.Context, rows [][]string, mode ImportMode) (*ImportResult, error) {
+	if len(rows) == 0 {
+		return nil, errors.BadRequest("", "Import file is empty")
+	} else if n, max := len(rows)-1, self.maxImportRows(); n > max {
+		return nil, errors.BadRequest("", "Too many rows, the limit is %d", max)
+	}
+	if mode == "" {
+		mode = ImportAllOrNone
+	} else if mode != ImportAllOrNone && mode != ImportValidOnly {
+		return nil, errors.BadRequest("", "Unsupported import mode: %s", mode)
+	}
+
+	result := &ImportResult{}
+	forms, rowNumbers := decodeRows[V](rows, result)
+	var (
+		items    []*T
+		itemRows []int
+	)
+	for i, form := range forms {
+		if err := form.Validate(); err != nil {
+			result.addError(rowNumbers[i], "", err)
+			continue
+		}
+		item, err := self.newItem(form)
+		if err != nil {
+			result.addError(rowNumbers[i], "", err)
+			continue
+		}
+		items = append(items, item)
+		itemRows = append(itemRows, rowNumbers[i])
+	}
+	if len(items) == 0 || (mode == ImportAllOrNone && len(result.Errors) > 0) {
+		result.sort()
+		return result, nil
+	}
+
+	err := self.Trans.Exec(ctx, func(ctx context.Context) error {
+		if self.Hooks.BeforeCreate != nil {
+			accepted := items[:0:0]
+			for i, item := range items {
+				if err := self.Hooks.BeforeCreate(ctx, item); err != nil {
+					if _, ok := errors.As(err); !ok {
+						return err
+					}
+					result.addError(itemRows[i], "", err)
+					continue
+				}
+				accepted = append(accepted, item)
+			}
+			if len(accepted) < len(items) && mode == ImportAllOrNone || len(accepted) == 0 {
+				return errImportRejected
+			}
+			items = accepted
+		}
+		if err := self.Repo.BatchCreate(ctx, items); err != nil {
+			return err
+		}
+		if self.Hooks.AfterCreate != nil {
+			for _, item := range items {
+				if err := self.Hooks.AfterCreate(ctx, item); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	result.sort()
+	if stderrors.Is(err, errImportRejected) {
+		return result, nil
+	} else if err != nil {
+		return nil, err
+	}
+	result.Created = len(items)
+	return result, nil
+}
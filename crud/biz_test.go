@@ -0,0 +1,324 @@
+package crud
+
+import (
+	"context"
+	stderrors "errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/puras/mog/contextx"
+	"github.com/puras/mog/dbx"
+	"github.com/puras/mog/errors"
+	"github.com/puras/mog/i18n"
+	"github.com/puras/mog/model"
+)
+
+type crudItem struct {
+	model.BaseModel
+	model.VersionModel
+	Name   string `json:"name"`
+	Remark string `json:"remark"`
+}
+
+type crudItemForm struct {
+	Name   string `json:"name"`
+	Remark string `json:"remark"`
+}
+
+func (f crudItemForm) Validate() error {
+	if f.Name == "" {
+		return errors.BadRequest("", "name is required")
+	}
+	return nil
+}
+
+func (f crudItemForm) FillTo(item *crudItem) error {
+	item.Name = f.Name
+	item.Remark = f.Remark
+	return nil
+}
+
+func newCrudBiz(t *testing.T) *CrudBiz[crudItem, crudItemForm] {
+	db, err := dbx.NewDB(dbx.Config{DBType: "sqlite3", DSN: filepath.Join(t.TempDir(), "crud.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&crudItem{}); err != nil {
+		t.Fatal(err)
+	}
+	return NewBiz[crudItem, crudItemForm](&dbx.Trans{DB: db}, NewRepo[crudItem](db, nil), nil)
+}
+
+func TestCrudBiz_UpdateIncrementsVersion(t *testing.T) {
+	biz := newCrudBiz(t)
+	ctx := context.Background()
+
+	item, err := biz.Create(ctx, crudItemForm{Name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	} else if item.Version != 1 {
+		t.Fatalf("expected initial version 1, got %d", item.Version)
+	}
+
+	if updated, err := biz.Update(contextx.NewIfMatch(ctx, 1), item.ID, crudItemForm{Name: "b"}); err != nil {
+		t.Fatal(err)
+	} else if updated.Version != 2 {
+		t.Fatalf("expected updated version 2, got %d", updated.Version)
+	}
+	saved, err := biz.Get(ctx, item.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Version != 2 || saved.Name != "b" {
+		t.Fatalf("unexpected item after update: version=%d name=%s", saved.Version, saved.Name)
+	}
+}
+
+func TestCrudBiz_UpdateStaleVersion(t *testing.T) {
+	biz := newCrudBiz(t)
+	ctx := context.Background()
+
+	item, err := biz.Create(ctx, crudItemForm{Name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := biz.Update(ctx, item.ID, crudItemForm{Name: "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = biz.Update(contextx.NewIfMatch(ctx, 1), item.ID, crudItemForm{Name: "c"})
+	if !errors.Equal(err, ErrPreconditionFailed) {
+		t.Fatalf("expected precondition failed, got %v", err)
+	}
+}
+
+func TestCrudRepo_UpdateConcurrentWriter(t *testing.T) {
+	biz := newCrudBiz(t)
+	ctx := context.Background()
+
+	item, err := biz.Create(ctx, crudItemForm{Name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := biz.Repo.Get(ctx, item.ID)
+	second, _ := biz.Repo.Get(ctx, item.ID)
+
+	first.Name = "first"
+	if err := biz.Repo.Update(ctx, item.ID, first); err != nil {
+		t.Fatal(err)
+	}
+	second.Name = "second"
+	if err := biz.Repo.Update(ctx, item.ID, second); !errors.Equal(err, ErrVersionConflict) {
+		t.Fatalf("expected version conflict, got %v", err)
+	} else if second.Version != 1 {
+		t.Fatalf("version should be restored on conflict, got %d", second.Version)
+	}
+}
+
+func TestCrudBiz_Patch(t *testing.T) {
+	biz := newCrudBiz(t)
+	ctx := context.Background()
+
+	item, err := biz.Create(ctx, crudItemForm{Name: "a", Remark: "keep"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patched, err := biz.Patch(contextx.NewIfMatch(ctx, 1), item.ID, Patch{Data: []byte(`{"name":"b"}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched.Name != "b" || patched.Remark != "keep" || patched.Version != 2 {
+		t.Fatalf("unexpected patched item: %+v", patched)
+	}
+
+	_, err = biz.Patch(ctx, item.ID, Patch{Type: JSONPatchType, Data: []byte(`[{"op":"replace","path":"/name","value":""}]`)})
+	if e, ok := errors.As(err); !ok || e.Code != 400 {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+}
+
+func TestCrudRepo_PatchChangedColumnsOnly(t *testing.T) {
+	biz := newCrudBiz(t)
+	ctx := context.Background()
+
+	item, err := biz.Create(ctx, crudItemForm{Name: "a", Remark: "old"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, _ := biz.Repo.Get(ctx, item.ID)
+	// 模拟其他字段被并发修改（不递增版本）
+	if err := biz.Repo.GetModelDB(ctx).Where("id=?", item.ID).UpdateColumn("remark", "new").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	changed := *old
+	changed.Name = "b"
+	if err := biz.Repo.Patch(ctx, item.ID, old, &changed); err != nil {
+		t.Fatal(err)
+	}
+	saved, _ := biz.Repo.Get(ctx, item.ID)
+	if saved.Name != "b" || saved.Remark != "new" {
+		t.Fatalf("patch should only write changed columns: %+v", saved)
+	}
+}
+
+func TestCrudBiz_BatchCreate(t *testing.T) {
+	biz := newCrudBiz(t)
+	ctx := context.Background()
+
+	_, err := biz.BatchCreate(ctx, []crudItemForm{{Name: "a"}, {}, {Name: "c"}})
+	var batchErr *BatchError
+	if !stderrors.As(err, &batchErr) || len(batchErr.Items) != 1 || batchErr.Items[0].Index != 1 {
+		t.Fatalf("expected per-item error at index 1, got %v", err)
+	}
+
+	items, err := biz.BatchCreate(ctx, []crudItemForm{{Name: "a"}, {Name: "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret, err := biz.Query(ctx, PageParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || ret.Total != 2 {
+		t.Fatalf("expected 2 items, got %d/%d", len(items), ret.Total)
+	}
+
+	biz.MaxBatchSize = 1
+	if _, err := biz.BatchCreate(ctx, []crudItemForm{{Name: "a"}, {Name: "b"}}); err == nil {
+		t.Fatal("expected batch size error")
+	}
+}
+
+func TestCrudBiz_BatchUpdateRollback(t *testing.T) {
+	biz := newCrudBiz(t)
+	ctx := context.Background()
+
+	items, err := biz.BatchCreate(ctx, []crudItemForm{{Name: "a"}, {Name: "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = biz.BatchUpdate(ctx, []BatchUpdateItem[crudItemForm]{
+		{Id: items[0].ID, Data: crudItemForm{Name: "a1"}},
+		{Id: "missing", Data: crudItemForm{Name: "x"}},
+	})
+	var batchErr *BatchError
+	if !stderrors.As(err, &batchErr) || batchErr.Items[0].Id != "missing" || batchErr.Items[0].Code != 404 {
+		t.Fatalf("expected not found item error, got %v", err)
+	}
+
+	// 同一 id 的第二次更新版本冲突，整批回滚
+	err = biz.BatchUpdate(ctx, []BatchUpdateItem[crudItemForm]{
+		{Id: items[0].ID, Data: crudItemForm{Name: "a1"}},
+		{Id: items[0].ID, Data: crudItemForm{Name: "a2"}},
+	})
+	if !stderrors.As(err, &batchErr) || batchErr.Items[0].Index != 1 || batchErr.Items[0].Code != 409 {
+		t.Fatalf("expected version conflict at index 1, got %v", err)
+	}
+	saved, _ := biz.Get(ctx, items[0].ID)
+	if saved.Name != "a" {
+		t.Fatalf("batch update should be rolled back, got %s", saved.Name)
+	}
+
+	err = biz.BatchUpdate(ctx, []BatchUpdateItem[crudItemForm]{
+		{Id: items[0].ID, Data: crudItemForm{Name: "a1"}},
+		{Id: items[1].ID, Data: crudItemForm{Name: "b1"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, _ = biz.Get(ctx, items[1].ID)
+	if saved.Name != "b1" {
+		t.Fatalf("expected b1, got %s", saved.Name)
+	}
+}
+
+func TestCrudBiz_BatchDelete(t *testing.T) {
+	biz := newCrudBiz(t)
+	ctx := context.Background()
+
+	items, err := biz.BatchCreate(ctx, []crudItemForm{{Name: "a"}, {Name: "b"}, {Name: "c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := biz.BatchDelete(ctx, []string{items[0].ID, "missing"}); err == nil {
+		t.Fatal("expected missing id error")
+	}
+	if err := biz.BatchDelete(ctx, []string{items[0].ID, items[1].ID}); err != nil {
+		t.Fatal(err)
+	}
+	ret, err := biz.Query(ctx, PageParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Total != 1 {
+		t.Fatalf("expected 1 item left, got %d", ret.Total)
+	}
+}
+
+func TestCrudBiz_Hooks(t *testing.T) {
+	biz := newCrudBiz(t)
+	ctx := context.Background()
+
+	biz.Hooks.BeforeCreate = func(ctx context.Context, item *crudItem) error {
+		if exists, err := dbx.Exists(ctx, biz.Repo.GetModelDB(ctx).Where("name=?", item.Name)); err != nil {
+			return err
+		} else if exists {
+			return errors.DataIsExists("", "name %s already exists", item.Name)
+		}
+		return nil
+	}
+	var oldName, newName string
+	biz.Hooks.AfterUpdate = func(ctx context.Context, old, item *crudItem) error {
+		oldName, newName = old.Name, item.Name
+		return nil
+	}
+	biz.Hooks.AfterDelete = func(ctx context.Context, item *crudItem) error {
+		if item.Name == "locked" {
+			return errors.DataNotAllowEdit("", "item is locked")
+		}
+		return nil
+	}
+
+	item, err := biz.Create(ctx, crudItemForm{Name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := biz.Create(ctx, crudItemForm{Name: "a"}); err == nil {
+		t.Fatal("expected BeforeCreate to abort duplicated name")
+	}
+
+	if _, err := biz.Update(ctx, item.ID, crudItemForm{Name: "locked"}); err != nil {
+		t.Fatal(err)
+	}
+	if oldName != "a" || newName != "locked" {
+		t.Fatalf("unexpected old/new in AfterUpdate: %s/%s", oldName, newName)
+	}
+
+	// AfterDelete 返回错误时删除被回滚
+	if err := biz.Delete(ctx, item.ID); err == nil {
+		t.Fatal("expected AfterDelete to abort")
+	}
+	if _, err := biz.Get(ctx, item.ID); err != nil {
+		t.Fatalf("delete should be rolled back: %v", err)
+	}
+}
+
+func TestCrudBiz_NotFoundUsesResourceName(t *testing.T) {
+	biz := newCrudBiz(t)
+	biz.ResourceName = "crud_item"
+	i18n.Add(i18n.LanguageZh, i18n.Bundle{"resource.crud_item": "条目"})
+	i18n.Add(i18n.LanguageEn, i18n.Bundle{"resource.crud_item": "Item"})
+
+	_, err := biz.Get(context.Background(), "missing")
+	e, ok := errors.As(err)
+	if !ok || e.Id != ErrNotFoundId || e.Detail != "条目不存在" || e.Params["id"] != "missing" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := i18n.Localize(i18n.LanguageEn, e); got != "Item not found" {
+		t.Fatalf("unexpected english message: %q", got)
+	}
+}
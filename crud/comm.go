@@ -1,6 +1,12 @@
 package crud
 
-import "github.com/puras/mog/dbx"
+import (
+	"encoding/json"
+
+	"github.com/puras/mog/dbx"
+	"github.com/puras/mog/errors"
+	"github.com/puras/mog/i18n"
+)
 
 type QueryParams interface {
 	GetPaginationParam() dbx.PaginationParam
@@ -14,3 +20,75 @@ type PageParams struct {
 func (self PageParams) GetPaginationParam() dbx.PaginationParam {
 	return self.PaginationParam
 }
+
+// DefaultMaxBatchSize 批量操作默认允许的最大条数
+const DefaultMaxBatchSize = 100
+
+// BatchUpdateItem 批量更新的单条数据
+type BatchUpdateItem[V any] struct {
+	Id   string `json:"id" binding:"required"`
+	Data V      `json:"data"`
+}
+
+// BatchDeleteParams 批量删除参数
+type BatchDeleteParams struct {
+	Ids []string `json:"ids" binding:"required"`
+}
+
+// ItemError 批量操作中单条数据的错误，Index 为请求中的下标
+type ItemError struct {
+	Index   int    `json:"index"`
+	Id      string `json:"id,omitempty"`
+	Code    int32  `json:"code"`
+	Message string `json:"message"`
+
+	err *errors.Error
+}
+
+// BatchError 批量操作失败时返回的逐条错误，整批操作不会生效
+type BatchError struct {
+	Items []ItemError
+}
+
+func (e *BatchError) Error() string {
+	b, _ := json.Marshal(e.Items)
+	return string(b)
+}
+
+func (e *BatchError) add(index int, id string, err error) {
+	er, ok := errors.As(err)
+	if !ok {
+		er = errors.FromError(errors.InternalServerError("", "%s", err.Error()))
+	}
+	e.Items = append(e.Items, ItemError{Index: index, Id: id, Code: er.Code, Message: er.Detail, err: er})
+}
+
+// Localize 按语言翻译各条错误的消息
+func (e *BatchError) Localize(lang string) []ItemError {
+	items := make([]ItemError, len(e.Items))
+	for i, item := range e.Items {
+		if item.err != nil {
+			item.Message = i18n.Localize(lang, item.err)
+		}
+		items[i] = item
+	}
+	return items
+}
+
+// collect 记录单条数据的业务错误（errors.Error），其他错误（如数据库错误）直接返回以中止整批操作
+func (e *BatchError) collect(index int, id string, err error) error {
+	if err == nil {
+		return nil
+	} else if _, ok := errors.As(err); !ok {
+		return err
+	}
+	e.add(index, id, err)
+	return nil
+}
+
+func (e *BatchError) err() error {
+	if len(e.Items) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,227 @@
+package crud
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/puras/mog/errors"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+
+	// DefaultExportBatchSize 导出时每批查询的行数
+	DefaultExportBatchSize = 500
+
+	exportTimeLayout = "2006-01-02 15:04:05"
+	exportSheet      = "Sheet1"
+)
+
+// ExportContentTypes 导出格式对应的 Content-Type
+var ExportContentTypes = map[string]string{
+	FormatCSV:  "text/csv; charset=utf-8",
+	FormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// Column 导出/导入的列，Header 取自字段的 export tag，未设置时使用 json 字段名。
+// export:"-" 或 json:"-" 的字段不导出。
+type Column struct {
+	Header string
+	Name   string // json 字段名
+	index  []int
+}
+
+// Columns 资源的列
+type Columns []Column
+
+// Headers 表头行
+func (cs Columns) Headers() []any {
+	header := make([]any, len(cs))
+	for i, c := range cs {
+		header[i] = c.Header
+	}
+	return header
+}
+
+// Values 数据行，item 为结构体或其指针
+func (cs Columns) Values(item any) []any {
+	v := reflect.ValueOf(item)
+	row := make([]any, len(cs))
+	for i, c := range cs {
+		row[i] = c.value(v)
+	}
+	return row
+}
+
+// ColumnsOf 按字段顺序解析结构体的列，匿名嵌入的结构体展开
+func ColumnsOf(t reflect.Type) Columns {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var columns Columns
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		header := f.Tag.Get("export")
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if header == "-" || name == "-" {
+			continue
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct && ft != timeType {
+			for _, c := range ColumnsOf(ft) {
+				c.index = append([]int{i}, c.index...)
+				columns = append(columns, c)
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if header == "" {
+			header = name
+		}
+		columns = append(columns, Column{Header: header, Name: name, index: []int{i}})
+	}
+	return columns
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// value 取出列的值，时间格式化为字符串，空指针为 nil
+func (c Column) value(v reflect.Value) any {
+	for _, i := range c.index {
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(exportTimeLayout)
+	}
+	return v.Interface()
+}
+
+// RowWriter 逐行写出导出数据，Flush 输出剩余数据，Close 释放资源（出错时不调用 Flush 直接 Close）
+type RowWriter interface {
+	WriteRow(values []any) error
+	Flush() error
+	Close() error
+}
+
+// NewRowWriter 创建指定格式的 RowWriter，CSV 经缓冲流式写入 w，
+// XLSX 由 excelize 的 StreamWriter 写入（超出内存缓冲时落临时文件），Flush 时输出到 w
+func NewRowWriter(format string, w io.Writer) (RowWriter, error) {
+	switch format {
+	case FormatCSV:
+		bw := bufio.NewWriter(w)
+		// 写入 BOM，避免 Excel 打开中文乱码
+		if _, err := bw.WriteString("\xEF\xBB\xBF"); err != nil {
+			return nil, err
+		}
+		return &csvWriter{w: csv.NewWriter(bw), bw: bw}, nil
+	case FormatXLSX:
+		f := excelize.NewFile()
+		sw, err := f.NewStreamWriter(exportSheet)
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		return &xlsxWriter{f: f, sw: sw, out: w}, nil
+	}
+	return nil, errors.BadRequest("", "Unsupported export format: %s", format)
+}
+
+type csvWriter struct {
+	w  *csv.Writer
+	bw *bufio.Writer
+}
+
+func (w *csvWriter) WriteRow(values []any) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		if v != nil {
+			record[i] = fmt.Sprint(neutralizeFormula(v))
+		}
+	}
+	return w.w.Write(record)
+}
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	if err := w.w.Error(); err != nil {
+		return err
+	}
+	return w.bw.Flush()
+}
+
+func (w *csvWriter) Close() error {
+	return nil
+}
+
+type xlsxWriter struct {
+	f   *excelize.File
+	sw  *excelize.StreamWriter
+	out io.Writer
+	row int
+}
+
+func (w *xlsxWriter) WriteRow(values []any) error {
+	w.row++
+	cell, err := excelize.CoordinatesToCellName(1, w.row)
+	if err != nil {
+		return err
+	}
+	// StreamWriter 把字符串写为字符串单元格，不会被当作公式，原样写入
+	return w.sw.SetRow(cell, values)
+}
+
+func (w *xlsxWriter) Flush() error {
+	if err := w.sw.Flush(); err != nil {
+		return err
+	}
+	return w.f.Write(w.out)
+}
+
+func (w *xlsxWriter) Close() error {
+	return w.f.Close()
+}
+
+// neutralizeFormula 以 =、+、-、@、制表符或回车开头的字符串前加 '，避免 CSV 被表格软件打开时当作公式执行，
+// 只处理字符串，数值等其他类型原样返回
+func neutralizeFormula(v any) any {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.String {
+		return v
+	}
+	s := rv.String()
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,136 @@
+package crud
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestColumnsOf(t *testing.T) {
+	type exportItem struct {
+		crudItem
+		Secret string `json:"secret" export:"-"`
+		Title  string `json:"title" export:"标题"`
+	}
+	columns := ColumnsOf(reflect.TypeFor[exportItem]())
+	var headers []string
+	for _, c := range columns {
+		headers = append(headers, c.Header)
+	}
+	got := strings.Join(headers, ",")
+	expected := "id,createdAt,updatedAt,createdBy,updatedBy,deletedBy,version,name,remark,标题"
+	if got != expected {
+		t.Fatalf("expected headers %s, got %s", expected, got)
+	}
+}
+
+func TestCrudApi_Export(t *testing.T) {
+	e, _ := newCrudEngine(t)
+	for _, name := range []string{"a", "b", "c"} {
+		if w := serve(e, http.MethodPost, "/items", `{"name":"`+name+`","remark":"r,`+name+`"}`); w.Code != http.StatusOK {
+			t.Fatal(w.Body.String())
+		}
+	}
+
+	w := serve(e, http.MethodGet, "/items/export", "")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Disposition"), `attachment; filename=export-`) {
+		t.Fatalf("unexpected export response: %d %v", w.Code, w.Header())
+	}
+	lines := strings.Split(strings.TrimSpace(strings.TrimPrefix(w.Body.String(), "\xEF\xBB\xBF")), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "id,") || !strings.HasSuffix(lines[1], `"r,a"`) {
+		t.Fatalf("unexpected csv: %q", w.Body.String())
+	}
+
+	w = serve(e, http.MethodGet, "/items/export?format=xlsx", "")
+	f, err := excelize.OpenReader(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := f.GetRows(exportSheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || rows[3][7] != "c" {
+		t.Fatalf("unexpected xlsx rows: %v", rows)
+	}
+
+	if w := serve(e, http.MethodGet, "/items/export?format=pdf", ""); w.Code != http.StatusBadRequest || w.Header().Get("Content-Disposition") != "" {
+		t.Fatalf("unsupported format should be rejected: %d", w.Code)
+	}
+}
+
+func TestRowWriter_NeutralizeFormula(t *testing.T) {
+	type remark string
+	values := []any{"=HYPERLINK(\"http://evil\")", "+1", "-1", "@SUM(A1)", "\tx", "\rx", remark("=1+1"), "a=b", -1, nil}
+	expected := []string{"'=HYPERLINK(\"http://evil\")", "'+1", "'-1", "'@SUM(A1)", "'\tx", "'\rx", "'=1+1", "a=b", "-1", ""}
+
+	var buf bytes.Buffer
+	w, err := NewRowWriter(FormatCSV, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow(values); err != nil {
+		t.Fatal(err)
+	} else if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	record, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\xEF\xBB\xBF"))).Read()
+	if err != nil || !reflect.DeepEqual(record, expected) {
+		t.Fatalf("unexpected csv record %q: %v", record, err)
+	}
+
+	buf.Reset()
+	if w, err = NewRowWriter(FormatXLSX, &buf); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err := w.WriteRow(values); err != nil {
+		t.Fatal(err)
+	} else if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	// XLSX 的字符串单元格不会被当作公式，原样保留
+	raw := []string{"=HYPERLINK(\"http://evil\")", "+1", "-1", "@SUM(A1)", "\tx", "\rx", "=1+1", "a=b", "-1"}
+	for i, v := range raw {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		got, _ := f.GetCellValue(exportSheet, cell)
+		formula, _ := f.GetCellFormula(exportSheet, cell)
+		if got != v || formula != "" {
+			t.Fatalf("unexpected xlsx cell %s: %q %q", cell, got, formula)
+		}
+	}
+}
+
+func TestCrudBiz_ExportInBatches(t *testing.T) {
+	biz := newCrudBiz(t)
+	biz.ExportBatchSize = 2
+	ctx := context.Background()
+	if _, err := biz.BatchCreate(ctx, []crudItemForm{{Name: "a"}, {Name: "b"}, {Name: "c"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var batches, total int
+	err := biz.Export(ctx, PageParams{}, func(items []*crudItem) error {
+		batches++
+		total += len(items)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batches != 2 || total != 3 {
+		t.Fatalf("expected 3 rows in 2 batches, got %d/%d", total, batches)
+	}
+}
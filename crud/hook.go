@@ -0,0 +1,65 @@
+package crud
+
+import (
+	"context"
+
+	"github.com/puras/mog/model"
+)
+
+// Hooks CrudBiz 的生命周期回调，均在写操作所在的事务中执行（ctx 中带有事务），
+// 返回错误（通常为 errors.Error）即中止操作并回滚。
+//
+// Update/Patch 的 old 为更新前的数据，item 为即将写入（After 时为已写入）的数据。
+type Hooks[T model.IModel] struct {
+	BeforeCreate func(ctx context.Context, item *T) error
+	AfterCreate  func(ctx context.Context, item *T) error
+	BeforeUpdate func(ctx context.Context, old, item *T) error
+	AfterUpdate  func(ctx context.Context, old, item *T) error
+	BeforeDelete func(ctx context.Context, item *T) error
+	AfterDelete  func(ctx context.Context, item *T) error
+}
+
+func (h Hooks[T]) create(ctx context.Context, item *T, fn func() error) error {
+	if h.BeforeCreate != nil {
+		if err := h.BeforeCreate(ctx, item); err != nil {
+			return err
+		}
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	if h.AfterCreate != nil {
+		return h.AfterCreate(ctx, item)
+	}
+	return nil
+}
+
+func (h Hooks[T]) update(ctx context.Context, old, item *T, fn func() error) error {
+	if h.BeforeUpdate != nil {
+		if err := h.BeforeUpdate(ctx, old, item); err != nil {
+			return err
+		}
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	if h.AfterUpdate != nil {
+		return h.AfterUpdate(ctx, old, item)
+	}
+	return nil
+}
+
+func (h Hooks[T]) delete(ctx context.Context, item *T, fn func() error) error {
+	if h.BeforeDelete != nil {
+		if err := h.BeforeDelete(ctx, item); err != nil {
+			return err
+		}
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	if h.AfterDelete != nil {
+		return h.AfterDelete(ctx, item)
+	}
+	return nil
+}
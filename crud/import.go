@@ -0,0 +1,272 @@
+package crud
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"mime/multipart"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/puras/mog/errors"
+	"github.com/puras/mog/i18n"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	// DefaultMaxImportRows 单次导入默认允许的最大数据行数
+	DefaultMaxImportRows = 5000
+	// DefaultMaxImportSize 导入文件默认的最大字节数
+	DefaultMaxImportSize = 10 << 20
+
+	// xlsxUnzipRatio XLSX 解压后允许的大小与上传大小上限的倍数
+	xlsxUnzipRatio = 20
+)
+
+type ImportMode string
+
+const (
+	ImportAllOrNone ImportMode = "all"   // 任一行校验失败则不导入任何数据
+	ImportValidOnly ImportMode = "valid" // 只导入校验通过的行
+)
+
+// ImportFile 导入接口的 multipart 表单，用于描述文档
+type ImportFile struct {
+	File   *multipart.FileHeader `json:"file" binding:"required" description:"CSV/XLSX 文件，第一行为表头"`
+	Format string                `json:"format,omitempty" description:"csv 或 xlsx，默认取文件扩展名"`
+	Mode   ImportMode            `json:"mode,omitempty" description:"all（默认）任一行失败都不导入；valid 只导入校验通过的行"`
+}
+
+// RowError 导入时某一行的错误，Row 为文件中的行号（表头为第 1 行）
+type RowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+
+	err *errors.Error
+}
+
+// ImportResult 导入结果
+type ImportResult struct {
+	Total   int        `json:"total"`
+	Created int        `json:"created"`
+	Errors  []RowError `json:"errors,omitempty"`
+}
+
+func (r *ImportResult) addError(row int, column string, err error) {
+	e, ok := errors.As(err)
+	if !ok {
+		r.Errors = append(r.Errors, RowError{Row: row, Column: column, Message: err.Error()})
+		return
+	}
+	r.Errors = append(r.Errors, RowError{Row: row, Column: column, Message: e.Detail, err: e})
+}
+
+// Localize 按语言翻译各行错误的消息
+func (r *ImportResult) Localize(lang string) {
+	for i, e := range r.Errors {
+		if e.err != nil {
+			r.Errors[i].Message = i18n.Localize(lang, e.err)
+		}
+	}
+}
+
+func (r *ImportResult) sort() {
+	slices.SortStableFunc(r.Errors, func(a, b RowError) int { return a.Row - b.Row })
+}
+
+// ReadRows 读取 CSV/XLSX 文件的行（XLSX 取第一个工作表），第一行为表头。
+// 最多读取 maxRows+1 行数据（多读一行用于判断是否超限），maxSize 为上传文件的大小上限，
+// 用于限制 XLSX 解压后的大小；两者为 0 时取 DefaultMaxImportRows、DefaultMaxImportSize
+func ReadRows(format string, r io.Reader, maxRows int, maxSize int64) ([][]string, error) {
+	if maxRows <= 0 {
+		maxRows = DefaultMaxImportRows
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultMaxImportSize
+	}
+	limit := maxRows + 2 // 表头 + maxRows + 1
+	switch format {
+	case FormatCSV:
+		br := bufio.NewReader(r)
+		if bom, _ := br.Peek(3); bytes.Equal(bom, []byte("\xEF\xBB\xBF")) {
+			_, _ = br.Discard(3)
+		}
+		cr := csv.NewReader(br)
+		cr.FieldsPerRecord = -1
+		var rows [][]string
+		for len(rows) < limit {
+			record, err := cr.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, errors.BadRequest("", "Failed to parse csv: %s", err.Error())
+			}
+			for i, v := range record {
+				record[i] = restoreFormula(v)
+			}
+			rows = append(rows, record)
+		}
+		return rows, nil
+	case FormatXLSX:
+		// XLSX 是 zip 压缩包，按上传大小限制解压后的总大小，防止压缩炸弹
+		f, err := excelize.OpenReader(r, excelize.Options{
+			UnzipSizeLimit:    maxSize * xlsxUnzipRatio,
+			UnzipXMLSizeLimit: maxSize * xlsxUnzipRatio,
+		})
+		if err != nil {
+			return nil, errors.BadRequest("", "Failed to parse xlsx: %s", err.Error())
+		}
+		defer f.Close()
+		it, err := f.Rows(f.GetSheetName(0))
+		if err != nil {
+			return nil, errors.BadRequest("", "Failed to parse xlsx: %s", err.Error())
+		}
+		defer it.Close()
+		var rows [][]string
+		for len(rows) < limit && it.Next() {
+			record, err := it.Columns()
+			if err != nil {
+				return nil, errors.BadRequest("", "Failed to parse xlsx: %s", err.Error())
+			}
+			rows = append(rows, record)
+		}
+		return rows, it.Error()
+	}
+	return nil, errors.BadRequest("", "Unsupported import format: %s", format)
+}
+
+// restoreFormula 去掉导出 CSV 时 neutralizeFormula 加上的 '，使导出的文件可以原样导回
+func restoreFormula(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+// decodeRows 按表头把每行数据映射到表单字段，表头可以是 export tag 或 json 字段名，
+// 无法识别的列忽略；返回的 forms 与 rowNumbers 一一对应，解析失败的行记录到 result
+func decodeRows[V any](rows [][]string, result *ImportResult) ([]V, []int) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	columns := ColumnsOf(reflect.TypeFor[V]())
+	mapping := make([]*Column, len(rows[0]))
+	for i, header := range rows[0] {
+		header = strings.TrimSpace(header)
+		for j, c := range columns {
+			if c.Header == header || c.Name == header {
+				mapping[i] = &columns[j]
+				break
+			}
+		}
+	}
+
+	var (
+		forms      []V
+		rowNumbers []int
+	)
+	for i, record := range rows[1:] {
+		row := i + 2
+		if isBlank(record) {
+			continue
+		}
+		result.Total++
+
+		form := new(V)
+		v := reflect.ValueOf(form).Elem()
+		ok := true
+		for j, cell := range record {
+			if j >= len(mapping) || mapping[j] == nil || cell == "" {
+				continue
+			}
+			if err := mapping[j].set(v, cell); err != nil {
+				result.addError(row, mapping[j].Header, err)
+				ok = false
+			}
+		}
+		if ok {
+			forms = append(forms, *form)
+			rowNumbers = append(rowNumbers, row)
+		}
+	}
+	return forms, rowNumbers
+}
+
+func isBlank(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+var importTimeLayouts = []string{exportTimeLayout, time.RFC3339, time.DateOnly}
+
+// set 把单元格文本转换为字段类型后赋值
+func (c Column) set(v reflect.Value, s string) error {
+	for _, i := range c.index {
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	s = strings.TrimSpace(s)
+	invalid := errors.InvalidParam("", "Invalid value %q", s)
+	if v.Type() == timeType {
+		for _, layout := range importTimeLayouts {
+			if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+				v.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return invalid
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return invalid
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return invalid
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return invalid
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return invalid
+		}
+		v.SetFloat(n)
+	default:
+		return errors.InvalidParam("", "Unsupported column type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package crud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/puras/mog/errors"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestColumn_Set(t *testing.T) {
+	type importForm struct {
+		Count   int        `json:"count"`
+		Enabled *bool      `json:"enabled"`
+		Price   float64    `json:"price"`
+		Birth   time.Time  `json:"birth"`
+		Expires *time.Time `json:"expires"`
+	}
+	rows := [][]string{
+		{"count", "enabled", "price", "birth", "expires", "unknown"},
+		{"3", "true", "1.5", "2020-01-02", "2020-01-02 03:04:05", "x"},
+		{"x", "", "", "", "", ""},
+	}
+	result := &ImportResult{}
+	forms, rowNumbers := decodeRows[importForm](rows, result)
+	if len(forms) != 1 || rowNumbers[0] != 2 || result.Total != 2 {
+		t.Fatalf("unexpected decode: %+v %v %+v", forms, rowNumbers, result)
+	}
+	f := forms[0]
+	if f.Count != 3 || f.Enabled == nil || !*f.Enabled || f.Price != 1.5 || f.Birth.Day() != 2 || f.Expires.Hour() != 3 {
+		t.Fatalf("unexpected form: %+v", f)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Row != 3 || result.Errors[0].Column != "count" {
+		t.Fatalf("unexpected errors: %+v", result.Errors)
+	}
+}
+
+func TestReadRows_Limits(t *testing.T) {
+	var csvData strings.Builder
+	csvData.WriteString("\xEF\xBB\xBFname\n")
+	f := excelize.NewFile()
+	f.SetSheetRow(exportSheet, "A1", &[]any{"name"})
+	for i := 1; i <= 100; i++ {
+		csvData.WriteString("n" + strconv.Itoa(i) + "\n")
+		f.SetSheetRow(exportSheet, "A"+strconv.Itoa(i+1), &[]any{"n" + strconv.Itoa(i)})
+	}
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 最多读取表头 + maxRows + 1 行
+	for format, data := range map[string][]byte{FormatCSV: []byte(csvData.String()), FormatXLSX: buf.Bytes()} {
+		rows, err := ReadRows(format, bytes.NewReader(data), 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(rows) != 12 || rows[0][0] != "name" || rows[11][0] != "n11" {
+			t.Fatalf("%s: unexpected rows %d %v", format, len(rows), rows[0])
+		}
+	}
+
+	// 解压后的大小超过上传上限的 xlsxUnzipRatio 倍时拒绝
+	if _, err := ReadRows(FormatXLSX, bytes.NewReader(buf.Bytes()), 10, 16); err == nil {
+		t.Fatal("expected unzip size limit error")
+	}
+}
+
+func TestCrudApi_ExportImportRoundTrip(t *testing.T) {
+	e, _ := newCrudEngine(t)
+	if w := serve(e, http.MethodPost, "/items", `{"name":"-5","remark":"=1+1"}`); w.Code != http.StatusOK {
+		t.Fatal(w.Body.String())
+	}
+	w := serve(e, http.MethodGet, "/items/export", "")
+	if !strings.Contains(w.Body.String(), "'=1+1") {
+		t.Fatalf("export should neutralize formulas: %q", w.Body.String())
+	}
+	if w := uploadImport(e, "/items/import", "items.csv", w.Body.Bytes()); w.Code != http.StatusOK {
+		t.Fatalf("unexpected import response: %d %s", w.Code, w.Body.String())
+	}
+
+	w = serve(e, http.MethodGet, "/items", "")
+	var res struct {
+		Data struct {
+			Items []crudItem `json:"items"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || len(res.Data.Items) != 2 {
+		t.Fatalf("unexpected items: %v %s", err, w.Body.String())
+	}
+	for _, item := range res.Data.Items {
+		if item.Name != "-5" || item.Remark != "=1+1" {
+			t.Fatalf("values should round-trip unchanged: %+v", item)
+		}
+	}
+}
+
+func TestCrudBiz_Import(t *testing.T) {
+	biz := newCrudBiz(t)
+	ctx := context.Background()
+	rows := [][]string{
+		{"name", "remark"},
+		{"a", "r1"},
+		{"", "missing name"},
+		{},
+		{"c", ""},
+	}
+
+	result, err := biz.Import(ctx, rows, ImportAllOrNone)
+	if err != nil {
+		t.Fatal(err)
+	} else if result.Total != 3 || result.Created != 0 || len(result.Errors) != 1 || result.Errors[0].Row != 3 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if n := countItems(t, biz); n != 0 {
+		t.Fatalf("all-or-none should not create any item, got %d", n)
+	}
+
+	result, err = biz.Import(ctx, rows, ImportValidOnly)
+	if err != nil {
+		t.Fatal(err)
+	} else if result.Created != 2 || len(result.Errors) != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if n := countItems(t, biz); n != 2 {
+		t.Fatalf("expected 2 items, got %d", n)
+	}
+
+	biz.MaxImportRows = 2
+	if _, err := biz.Import(ctx, rows, ImportValidOnly); err == nil {
+		t.Fatal("expected too many rows error")
+	}
+}
+
+func TestCrudBiz_ImportHookRejectsRow(t *testing.T) {
+	biz := newCrudBiz(t)
+	ctx := context.Background()
+	biz.Hooks.BeforeCreate = func(ctx context.Context, item *crudItem) error {
+		if item.Name == "b" {
+			return errors.BadRequest("", "name b is not allowed")
+		}
+		return nil
+	}
+	rows := [][]string{{"name"}, {"a"}, {"b"}}
+
+	result, err := biz.Import(ctx, rows, ImportAllOrNone)
+	if err != nil {
+		t.Fatal(err)
+	} else if result.Created != 0 || len(result.Errors) != 1 || result.Errors[0].Row != 3 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if result, _ = biz.Import(ctx, rows, ImportValidOnly); result.Created != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+}
+
+func countItems(t *testing.T, biz *CrudBiz[crudItem, crudItemForm]) int {
+	var n int
+	err := biz.Export(context.Background(), PageParams{}, func(items []*crudItem) error {
+		n += len(items)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func uploadImport(e http.Handler, path, filename string, data []byte) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	fw, _ := mw.CreateFormFile("file", filename)
+	fw.Write(data)
+	mw.Close()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	e.ServeHTTP(w, req)
+	return w
+}
+
+func TestCrudApi_Import(t *testing.T) {
+	e, _ := newCrudEngine(t)
+
+	w := uploadImport(e, "/items/import", "items.csv", []byte("\xEF\xBB\xBFname,remark\na,x\n,y\n"))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"row":3`) {
+		t.Fatalf("unexpected import response: %d %s", w.Code, w.Body.String())
+	}
+
+	f := excelize.NewFile()
+	f.SetSheetRow(exportSheet, "A1", &[]any{"name", "remark"})
+	f.SetSheetRow(exportSheet, "A2", &[]any{"a", "x"})
+	f.SetSheetRow(exportSheet, "A3", &[]any{"", "y"})
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = uploadImport(e, "/items/import?mode=valid", "items.xlsx", buf.Bytes())
+	var res struct {
+		Data ImportResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("unexpected import response: %d %s", w.Code, w.Body.String())
+	} else if res.Data.Created != 1 || len(res.Data.Errors) != 1 {
+		t.Fatalf("unexpected import result: %+v", res.Data)
+	}
+
+	if w := uploadImport(e, "/items/import", "items.pdf", []byte("x")); w.Code != http.StatusBadRequest {
+		t.Fatalf("unsupported format should be rejected: %d", w.Code)
+	}
+}
@@ -0,0 +1,328 @@
+package crud
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/puras/mog/errors"
+)
+
+const (
+	MergePatchType = "application/merge-patch+json" // RFC 7396
+	JSONPatchType  = "application/json-patch+json"  // RFC 6902
+)
+
+// Patch 局部更新内容，Type 为空时按 JSON Merge Patch 处理
+type Patch struct {
+	Type string
+	Data []byte
+}
+
+// Apply 将补丁应用到 JSON 文档上，返回新的文档
+func (p Patch) Apply(doc []byte) ([]byte, error) {
+	if p.Type == JSONPatchType {
+		return JSONPatch(doc, p.Data)
+	}
+	return MergePatch(doc, p.Data)
+}
+
+// MergePatch 按 RFC 7396 合并补丁：对象递归合并，null 表示删除字段，其余值直接替换
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decodeJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decodeJSON(patch)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergePatch(target, p))
+}
+
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = mergePatch(t[k], v)
+		}
+	}
+	return t
+}
+
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// JSONPatch 按 RFC 6902 依次执行 add/remove/replace/move/copy/test 操作，任一操作失败则整体失败
+func JSONPatch(doc, patch []byte) ([]byte, error) {
+	target, err := decodeJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	var ops []patchOperation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, errors.BadRequest("invalid_patch", "Invalid json patch: %s", err.Error())
+	}
+
+	for _, op := range ops {
+		if target, err = applyOperation(target, op); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(target)
+}
+
+func applyOperation(doc any, op patchOperation) (any, error) {
+	if op.Path == nil {
+		return nil, invalidPatch("missing path in %q operation", op.Op)
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, invalidPatch("missing value in %q operation", op.Op)
+		}
+		value, err := decodeJSON(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		switch op.Op {
+		case "add":
+			return addValue(doc, path, value)
+		case "replace":
+			return replaceValue(doc, path, value)
+		}
+		current, err := getValue(doc, path)
+		if err != nil {
+			return nil, err
+		} else if !reflect.DeepEqual(current, value) {
+			return nil, errors.Conflict("patch_test_failed", "Test operation failed at %q", *op.Path)
+		}
+		return doc, nil
+	case "remove":
+		return removeValue(doc, path)
+	case "move", "copy":
+		if op.From == nil {
+			return nil, invalidPatch("missing from in %q operation", op.Op)
+		}
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := getValue(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			// 复制一份，避免后续操作同时修改两处
+			if value, err = deepCopy(value); err != nil {
+				return nil, err
+			}
+		} else {
+			if *op.Path != *op.From && strings.HasPrefix(*op.Path, *op.From+"/") {
+				return nil, invalidPatch("cannot move %q into its child %q", *op.From, *op.Path)
+			}
+			if doc, err = removeValue(doc, from); err != nil {
+				return nil, err
+			}
+		}
+		return addValue(doc, path, value)
+	default:
+		return nil, invalidPatch("unsupported operation %q", op.Op)
+	}
+}
+
+func getValue(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			v, ok := node[token]
+			if !ok {
+				return nil, pathNotFound(path)
+			}
+			doc = v
+		case []any:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, pathNotFound(path)
+		}
+	}
+	return doc, nil
+}
+
+func addValue(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return applyAt(doc, path, func(container any, key string) (any, error) {
+		switch node := container.(type) {
+		case map[string]any:
+			node[key] = value
+			return node, nil
+		case []any:
+			i := len(node)
+			if key != "-" {
+				var err error
+				if i, err = arrayIndex(key, len(node)); err != nil {
+					return nil, err
+				}
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+		return nil, pathNotFound(path)
+	})
+}
+
+func removeValue(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, invalidPatch("cannot remove the whole document")
+	}
+	return applyAt(doc, path, func(container any, key string) (any, error) {
+		switch node := container.(type) {
+		case map[string]any:
+			if _, ok := node[key]; !ok {
+				return nil, pathNotFound(path)
+			}
+			delete(node, key)
+			return node, nil
+		case []any:
+			i, err := arrayIndex(key, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(node[:i], node[i+1:]...), nil
+		}
+		return nil, pathNotFound(path)
+	})
+}
+
+func replaceValue(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return applyAt(doc, path, func(container any, key string) (any, error) {
+		switch node := container.(type) {
+		case map[string]any:
+			if _, ok := node[key]; !ok {
+				return nil, pathNotFound(path)
+			}
+			node[key] = value
+			return node, nil
+		case []any:
+			i, err := arrayIndex(key, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			node[i] = value
+			return node, nil
+		}
+		return nil, pathNotFound(path)
+	})
+}
+
+// applyAt 定位到 path 的父节点执行 fn，并把修改后的节点逐级写回（数组增删会产生新的切片）
+func applyAt(node any, path []string, fn func(container any, key string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(node, path[0])
+	}
+	switch n := node.(type) {
+	case map[string]any:
+		child, ok := n[path[0]]
+		if !ok {
+			return nil, pathNotFound(path)
+		}
+		v, err := applyAt(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n[path[0]] = v
+		return n, nil
+	case []any:
+		i, err := arrayIndex(path[0], len(n)-1)
+		if err != nil {
+			return nil, err
+		}
+		v, err := applyAt(n[i], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = v
+		return n, nil
+	}
+	return nil, pathNotFound(path)
+}
+
+// parsePointer 解析 RFC 6901 JSON Pointer
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, invalidPatch("invalid json pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func arrayIndex(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > max || (len(token) > 1 && token[0] == '0') {
+		return 0, invalidPatch("invalid array index %q", token)
+	}
+	return i, nil
+}
+
+func decodeJSON(data []byte) (any, error) {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, errors.BadRequest("invalid_patch", "Invalid json: %s", err.Error())
+	}
+	return v, nil
+}
+
+func deepCopy(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSON(b)
+}
+
+func invalidPatch(format string, a ...any) error {
+	return errors.BadRequest("invalid_patch", format, a...)
+}
+
+func pathNotFound(path []string) error {
+	return invalidPatch("path %q not found", "/"+strings.Join(path, "/"))
+}
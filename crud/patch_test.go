@@ -0,0 +1,59 @@
+package crud
+
+import (
+	"testing"
+
+	"github.com/puras/mog/errors"
+)
+
+const patchDoc = `{"name":"a","tags":["x","y"],"meta":{"color":"red","size":1}}`
+
+func TestMergePatch(t *testing.T) {
+	ret, err := MergePatch([]byte(patchDoc), []byte(`{"name":"b","meta":{"color":null,"weight":2},"tags":["z"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"meta":{"size":1,"weight":2},"name":"b","tags":["z"]}`
+	if string(ret) != expected {
+		t.Fatalf("expected %s, got %s", expected, ret)
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	patch := `[
+		{"op":"test","path":"/name","value":"a"},
+		{"op":"replace","path":"/name","value":"b"},
+		{"op":"add","path":"/tags/1","value":"w"},
+		{"op":"add","path":"/tags/-","value":"z"},
+		{"op":"remove","path":"/meta/color"},
+		{"op":"copy","from":"/meta/size","path":"/meta/weight"},
+		{"op":"move","from":"/tags/0","path":"/first"}
+	]`
+	ret, err := JSONPatch([]byte(patchDoc), []byte(patch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"first":"x","meta":{"size":1,"weight":1},"name":"b","tags":["w","y","z"]}`
+	if string(ret) != expected {
+		t.Fatalf("expected %s, got %s", expected, ret)
+	}
+}
+
+func TestJSONPatch_Errors(t *testing.T) {
+	cases := map[string]struct {
+		patch string
+		code  int32
+	}{
+		"test failed":     {`[{"op":"test","path":"/name","value":"b"}]`, 409},
+		"missing path":    {`[{"op":"replace","path":"/nope","value":1}]`, 400},
+		"bad index":       {`[{"op":"add","path":"/tags/5","value":1}]`, 400},
+		"unsupported op":  {`[{"op":"merge","path":"/name","value":1}]`, 400},
+		"move into child": {`[{"op":"move","from":"/meta","path":"/meta/inner"}]`, 400},
+	}
+	for name, c := range cases {
+		_, err := JSONPatch([]byte(patchDoc), []byte(c.patch))
+		if e, ok := errors.As(err); !ok || e.Code != c.code {
+			t.Errorf("%s: expected code %d, got %v", name, c.code, err)
+		}
+	}
+}
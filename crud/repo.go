@@ -2,7 +2,7 @@ package crud
 
 import (
 	"context"
-	"time"
+	"reflect"
 
 	"github.com/puras/mog/dbx"
 	"github.com/puras/mog/errors"
@@ -13,13 +13,30 @@ import (
 type ICrudRepo[T model.IModel] interface {
 	GetModelDB(ctx context.Context) *gorm.DB
 	Query(ctx context.Context, params QueryParams, pageParams dbx.PaginationParam, opts ...dbx.QueryOptions) (*dbx.PaginationResult, error)
+	Export(ctx context.Context, params QueryParams, batchSize int, fn func(items []*T) error, opts ...dbx.QueryOptions) error
 	Get(ctx context.Context, id string, opts ...dbx.QueryOptions) (*T, error)
+	GetByIds(ctx context.Context, ids []string, opts ...dbx.QueryOptions) ([]*T, error)
 	Create(ctx context.Context, item *T) error
+	BatchCreate(ctx context.Context, items []*T) error
 	Update(ctx context.Context, id string, item *T) error
+	Patch(ctx context.Context, id string, old, item *T) error
 	Delete(ctx context.Context, id string) error
+	BatchDelete(ctx context.Context, ids []string) error
 	HardDelete(ctx context.Context, id string) error
+	Restore(ctx context.Context, id string) error
 }
 
+// VersionColumn 乐观锁版本号字段，对应 model.VersionModel
+const VersionColumn = "version"
+
+// ErrNotFoundId 数据不存在时的错误 Id，消息模板参数为 resource、id
+const ErrNotFoundId = "resource_not_found"
+
+var ErrVersionConflict = errors.Conflict("version_conflict", "数据已被修改，请刷新后重试")
+
+// ErrPreconditionFailed If-Match 携带的版本与数据当前版本不一致
+var ErrPreconditionFailed = errors.PreconditionFailed("precondition_failed", "数据已被修改，请刷新后重试")
+
 type FillQueryParametersFunc func(ctx context.Context, db *gorm.DB, params QueryParams)
 
 type CrudRepo[T model.IModel] struct {
@@ -47,13 +64,37 @@ func (self *CrudRepo[T]) Query(ctx context.Context, params QueryParams, pagePara
 		self.FillQueryParametersFunc(ctx, db, params)
 	}
 
-	dbx.NotDeleted(db)
-
 	var list []*T
 	pr, err := dbx.WrapPageQuery(ctx, db, pageParams, opt, &list)
 	return dbx.WrapPaginationResult(pr, list, err)
 }
 
+// Export 不分页地执行与 Query 相同的条件查询，按主键分批（FindInBatches）交给 fn 处理，
+// 因此会忽略 opts 中的排序
+func (self *CrudRepo[T]) Export(ctx context.Context, params QueryParams, batchSize int, fn func(items []*T) error, opts ...dbx.QueryOptions) error {
+	var opt dbx.QueryOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	db := self.GetModelDB(ctx)
+	if self.FillQueryParametersFunc != nil {
+		self.FillQueryParametersFunc(ctx, db, params)
+	}
+	if len(opt.SelectFields) > 0 {
+		db = db.Select(opt.SelectFields)
+	}
+	if len(opt.OmitFields) > 0 {
+		db = db.Omit(opt.OmitFields...)
+	}
+
+	var list []*T
+	ret := db.FindInBatches(&list, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(list)
+	})
+	return errors.WithStack(ret.Error)
+}
+
 func (self *CrudRepo[T]) Get(ctx context.Context, id string, opts ...dbx.QueryOptions) (*T, error) {
 	var opt dbx.QueryOptions
 	if len(opts) > 0 {
@@ -63,7 +104,6 @@ func (self *CrudRepo[T]) Get(ctx context.Context, id string, opts ...dbx.QueryOp
 	item := new(T)
 	db := self.GetModelDB(ctx)
 	dbx.Where(db, "id", id)
-	dbx.NotDeleted(db)
 	ok, err := dbx.FindOne(ctx, db, opt, item)
 	if err != nil {
 		return nil, errors.WithStack(err)
@@ -73,30 +113,120 @@ func (self *CrudRepo[T]) Get(ctx context.Context, id string, opts ...dbx.QueryOp
 	return item, nil
 }
 
+func (self *CrudRepo[T]) GetByIds(ctx context.Context, ids []string, opts ...dbx.QueryOptions) ([]*T, error) {
+	var opt dbx.QueryOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	var list []*T
+	db := self.GetModelDB(ctx).Where("id IN ?", ids)
+	err := dbx.FindAll(ctx, db, opt, &list)
+	return list, errors.WithStack(err)
+}
+
 func (self *CrudRepo[T]) Create(ctx context.Context, item *T) error {
 	ret := self.GetModelDB(ctx).Create(item)
 	return errors.WithStack(ret.Error)
 }
 
+// createBatchSize 批量创建时每条 INSERT 语句包含的最大行数
+const createBatchSize = 100
+
+func (self *CrudRepo[T]) BatchCreate(ctx context.Context, items []*T) error {
+	ret := self.GetModelDB(ctx).CreateInBatches(items, createBatchSize)
+	return errors.WithStack(ret.Error)
+}
+
+// Update 更新数据，模型实现 model.IVersioned 时按当前版本号更新并递增版本，
+// 版本不匹配（数据已被他人修改）时返回 ErrVersionConflict
 func (self *CrudRepo[T]) Update(ctx context.Context, id string, item *T) error {
+	db := self.GetModelDB(ctx).Select("*").Omit("created_at", "deleted", "deleted_at")
+	return self.updates(db, id, item)
+}
+
+// Patch 只更新 item 相对 old 发生变化的字段，版本校验同 Update
+func (self *CrudRepo[T]) Patch(ctx context.Context, id string, old, item *T) error {
 	db := self.GetModelDB(ctx)
+	if err := db.Statement.Parse(new(T)); err != nil {
+		return errors.WithStack(err)
+	}
+
+	oldValue, newValue := reflect.ValueOf(old).Elem(), reflect.ValueOf(item).Elem()
+	var columns []string
+	for _, field := range db.Statement.Schema.Fields {
+		if field.DBName == "" || field.PrimaryKey || unpatchableColumns[field.DBName] {
+			continue
+		}
+		ov, _ := field.ValueOf(ctx, oldValue)
+		nv, _ := field.ValueOf(ctx, newValue)
+		if !reflect.DeepEqual(ov, nv) {
+			columns = append(columns, field.DBName)
+		}
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+	if _, ok := any(item).(model.IVersioned); ok {
+		columns = append(columns, VersionColumn)
+	}
+	return self.updates(db.Select(columns), id, item)
+}
+
+// unpatchableColumns 由框架维护、不参与局部更新比较的字段
+var unpatchableColumns = map[string]bool{
+	"created_at": true, "updated_at": true, "deleted": true, "deleted_at": true, VersionColumn: true,
+}
+
+func (self *CrudRepo[T]) updates(db *gorm.DB, id string, item *T) error {
 	dbx.Where(db, "id", id)
-	ret := db.Select("*").Omit("created_at", "deleted", "deleted_at").Updates(item)
-	return errors.WithStack(ret.Error)
+
+	versioned, ok := any(item).(model.IVersioned)
+	if !ok {
+		ret := db.Updates(item)
+		return errors.WithStack(ret.Error)
+	}
+
+	version := versioned.GetVersion()
+	dbx.Where(db, VersionColumn, version)
+	versioned.SetVersion(version + 1)
+	ret := db.Updates(item)
+	if err := ret.Error; err != nil {
+		versioned.SetVersion(version)
+		return errors.WithStack(err)
+	} else if ret.RowsAffected == 0 {
+		versioned.SetVersion(version)
+		return ErrVersionConflict
+	}
+	return nil
 }
 
-// Delete 逻辑删除
+// Delete 逻辑删除，由 dbx.SoftDeletePlugin 改写为软删除
 func (self *CrudRepo[T]) Delete(ctx context.Context, id string) error {
 	db := self.GetModelDB(ctx)
 	dbx.Where(db, "id", id)
-	ret := db.Omit("updated_at").Updates(map[string]interface{}{"deleted": true, "deleted_at": time.Now()})
+	ret := db.Delete(new(T))
+	return errors.WithStack(ret.Error)
+}
+
+// BatchDelete 按 id 批量逻辑删除
+func (self *CrudRepo[T]) BatchDelete(ctx context.Context, ids []string) error {
+	ret := self.GetModelDB(ctx).Where("id IN ?", ids).Delete(new(T))
 	return errors.WithStack(ret.Error)
 }
 
 // HardDelete 物理删除
 func (self *CrudRepo[T]) HardDelete(ctx context.Context, id string) error {
-	db := self.GetModelDB(ctx)
+	db := self.GetModelDB(ctx).Unscoped()
 	dbx.Where(db, "id", id)
 	ret := db.Delete(new(T))
 	return errors.WithStack(ret.Error)
 }
+
+// Restore 恢复逻辑删除的数据
+func (self *CrudRepo[T]) Restore(ctx context.Context, id string) error {
+	db := self.GetModelDB(ctx)
+	dbx.Where(db, "id", id)
+	ret := dbx.Restore(db)
+	return errors.WithStack(ret.Error)
+}
@@ -0,0 +1,181 @@
+package crud
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/puras/mog/config"
+	"github.com/puras/mog/openapi"
+	"github.com/puras/mog/web"
+)
+
+// 标准 REST 路由名称，用于 Register 的选项
+const (
+	RouteQuery       = "query"
+	RouteGet         = "get"
+	RouteCreate      = "create"
+	RouteUpdate      = "update"
+	RoutePatch       = "patch"
+	RouteDelete      = "delete"
+	RouteBatchCreate = "batch_create"
+	RouteBatchUpdate = "batch_update"
+	RouteBatchDelete = "batch_delete"
+	RouteExport      = "export"
+	RouteImport      = "import"
+)
+
+// IRestApi 可由 Register 挂载的处理器集合，CrudApi 均实现了该接口
+type IRestApi interface {
+	Query(c *gin.Context)
+	Get(c *gin.Context)
+	Create(c *gin.Context)
+	Update(c *gin.Context)
+	Patch(c *gin.Context)
+	Delete(c *gin.Context)
+	BatchCreate(c *gin.Context)
+	BatchUpdate(c *gin.Context)
+	BatchDelete(c *gin.Context)
+	Export(c *gin.Context)
+	Import(c *gin.Context)
+}
+
+type routeDescriber interface {
+	describeRoute(name string, r *openapi.Route)
+}
+
+// Route 注册的路由，Path 为包含 group 前缀的完整路径
+type Route struct {
+	Name       string
+	Method     string
+	Path       string
+	Handler    gin.HandlerFunc
+	Middleware []gin.HandlerFunc
+}
+
+type registerOptions struct {
+	disabled   map[string]bool
+	handlers   map[string]gin.HandlerFunc
+	middleware []gin.HandlerFunc
+	routeMW    map[string][]gin.HandlerFunc
+}
+
+type RegisterOption func(*registerOptions)
+
+// WithoutRoutes 不注册指定的路由
+func WithoutRoutes(names ...string) RegisterOption {
+	return func(o *registerOptions) {
+		for _, name := range names {
+			o.disabled[name] = true
+		}
+	}
+}
+
+// WithHandler 使用自定义处理器替换指定路由
+func WithHandler(name string, handler gin.HandlerFunc) RegisterOption {
+	return func(o *registerOptions) {
+		o.handlers[name] = handler
+	}
+}
+
+// WithMiddleware 为所有路由添加中间件
+func WithMiddleware(middleware ...gin.HandlerFunc) RegisterOption {
+	return func(o *registerOptions) {
+		o.middleware = append(o.middleware, middleware...)
+	}
+}
+
+// WithRouteMiddleware 为指定路由添加中间件（如权限校验），在 WithMiddleware 之后执行
+func WithRouteMiddleware(name string, middleware ...gin.HandlerFunc) RegisterOption {
+	return func(o *registerOptions) {
+		o.routeMW[name] = append(o.routeMW[name], middleware...)
+	}
+}
+
+// Register 为资源挂载标准 REST 路由：
+//
+//	GET    path           Query
+//	GET    path/:id       Get
+//	POST   path           Create
+//	PUT    path/:id       Update
+//	PATCH  path/:id       Patch
+//	DELETE path/:id       Delete
+//	POST   path/batch     BatchCreate
+//	PUT    path/batch     BatchUpdate
+//	DELETE path/batch     BatchDelete
+//	GET    path/export    Export
+//	POST   path/import    Import
+//
+// group 尚未位于 config.General.ContextPath 之下时会自动加上该前缀。
+// api 为 CrudApi 时，路由会按 T/D/F 登记到 openapi.Default 用于生成文档，重复注册（如多个 engine）只保留一份。
+func Register(group gin.IRouter, path string, api IRestApi, opts ...RegisterOption) []Route {
+	o := &registerOptions{
+		disabled: map[string]bool{},
+		handlers: map[string]gin.HandlerFunc{},
+		routeMW:  map[string][]gin.HandlerFunc{},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if prefix := strings.TrimSuffix(config.C.General.ContextPath, "/"); prefix != "" {
+		if !underPrefix(group, prefix) {
+			group = group.Group(prefix)
+		}
+	}
+
+	path = "/" + strings.Trim(path, "/")
+	item := path + "/:" + web.PARAM_ID
+	batch := path + "/batch"
+	routes := []Route{
+		{Name: RouteQuery, Method: http.MethodGet, Path: path, Handler: api.Query},
+		{Name: RouteGet, Method: http.MethodGet, Path: item, Handler: api.Get},
+		{Name: RouteCreate, Method: http.MethodPost, Path: path, Handler: api.Create},
+		{Name: RouteUpdate, Method: http.MethodPut, Path: item, Handler: api.Update},
+		{Name: RoutePatch, Method: http.MethodPatch, Path: item, Handler: api.Patch},
+		{Name: RouteDelete, Method: http.MethodDelete, Path: item, Handler: api.Delete},
+		{Name: RouteBatchCreate, Method: http.MethodPost, Path: batch, Handler: api.BatchCreate},
+		{Name: RouteBatchUpdate, Method: http.MethodPut, Path: batch, Handler: api.BatchUpdate},
+		{Name: RouteBatchDelete, Method: http.MethodDelete, Path: batch, Handler: api.BatchDelete},
+		{Name: RouteExport, Method: http.MethodGet, Path: path + "/export", Handler: api.Export},
+		{Name: RouteImport, Method: http.MethodPost, Path: path + "/import", Handler: api.Import},
+	}
+
+	base := ""
+	if g, ok := group.(interface{ BasePath() string }); ok {
+		base = strings.TrimSuffix(g.BasePath(), "/")
+	}
+	registered := make([]Route, 0, len(routes))
+	for _, r := range routes {
+		if o.disabled[r.Name] {
+			continue
+		}
+		if h, ok := o.handlers[r.Name]; ok {
+			r.Handler = h
+		}
+		r.Middleware = append(append([]gin.HandlerFunc{}, o.middleware...), o.routeMW[r.Name]...)
+		group.Handle(r.Method, r.Path, append(r.Middleware, r.Handler)...)
+		r.Path = base + r.Path
+		registered = append(registered, r)
+	}
+
+	if d, ok := api.(routeDescriber); ok {
+		tag := strings.TrimPrefix(path, "/")
+		for _, r := range registered {
+			route := openapi.Route{Method: r.Method, Path: r.Path, Tags: []string{tag}}
+			d.describeRoute(r.Name, &route)
+			openapi.Add(route)
+		}
+	}
+	return registered
+}
+
+// underPrefix 判断 group 是否已位于 prefix 之下，按路径段比较，/apiv2 不在 /api 之下
+func underPrefix(group gin.IRouter, prefix string) bool {
+	g, ok := group.(interface{ BasePath() string })
+	if !ok {
+		return false
+	}
+	base := strings.TrimSuffix(g.BasePath(), "/")
+	return base == prefix || strings.HasPrefix(base, prefix+"/")
+}
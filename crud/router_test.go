@@ -0,0 +1,168 @@
+package crud
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/puras/mog/config"
+	"github.com/puras/mog/errors"
+	"github.com/puras/mog/openapi"
+	"github.com/puras/mog/web"
+)
+
+func newCrudEngine(t *testing.T, opts ...RegisterOption) (*gin.Engine, []Route) {
+	gin.SetMode(gin.TestMode)
+	biz := newCrudBiz(t)
+	e := gin.New()
+	routes := Register(e, "/items", NewApi[crudItem, PageParams, crudItemForm](biz), opts...)
+	return e, routes
+}
+
+func serve(e *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	e.ServeHTTP(w, req)
+	return w
+}
+
+func TestRegister_Routes(t *testing.T) {
+	e, routes := newCrudEngine(t)
+	if len(routes) != 11 {
+		t.Fatalf("expected 11 routes, got %d", len(routes))
+	}
+
+	w := serve(e, http.MethodPost, "/items", `{"name":"a"}`)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"1"` {
+		t.Fatalf("create failed: %d %s", w.Code, w.Body.String())
+	}
+	w = serve(e, http.MethodGet, "/items", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"total":1`) {
+		t.Fatalf("query failed: %d %s", w.Code, w.Body.String())
+	}
+	w = serve(e, http.MethodDelete, "/items/batch", `{"ids":["missing"]}`)
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), `"id":"missing"`) {
+		t.Fatalf("batch delete should report per-item errors: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCrudApi_ConditionalWrites(t *testing.T) {
+	e, _ := newCrudEngine(t)
+	var res struct {
+		Data crudItem `json:"data"`
+	}
+	w := serve(e, http.MethodPost, "/items", `{"name":"a"}`)
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	conditional := func(method, etag, contentType, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/items/"+res.Data.ID, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("If-Match", etag)
+		e.ServeHTTP(w, req)
+		return w
+	}
+
+	// 成功的写入返回新的 ETag，可直接用于下一次条件写入
+	w = conditional(http.MethodPut, `"1"`, "application/json", `{"name":"b"}`)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Fatalf("update should return the new etag: %d %v", w.Code, w.Header())
+	}
+	w = conditional(http.MethodPatch, w.Header().Get("ETag"), MergePatchType, `{"name":"c"}`)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"3"` {
+		t.Fatalf("patch should return the new etag: %d %v", w.Code, w.Header())
+	}
+
+	for _, method := range []string{http.MethodPut, http.MethodPatch} {
+		if w := conditional(method, `"1"`, MergePatchType, `{"name":"d"}`); w.Code != http.StatusPreconditionFailed {
+			t.Fatalf("%s with stale If-Match should return 412, got %d %s", method, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRegister_Options(t *testing.T) {
+	old := config.C.General.ContextPath
+	config.C.General.ContextPath = "/api"
+	defer func() { config.C.General.ContextPath = old }()
+
+	deny := func(c *gin.Context) {
+		web.ResError(c, errors.Forbidden("", "denied"))
+	}
+	e, routes := newCrudEngine(t,
+		WithoutRoutes(RouteBatchCreate, RouteBatchUpdate, RouteBatchDelete),
+		WithHandler(RouteGet, func(c *gin.Context) { web.ResSuccess(c, c.Param(web.PARAM_ID)) }),
+		WithRouteMiddleware(RouteDelete, deny),
+	)
+	if len(routes) != 8 || routes[0].Path != "/api/items" {
+		t.Fatalf("unexpected routes: %+v", routes)
+	}
+
+	if w := serve(e, http.MethodGet, "/api/items/abc", ""); !strings.Contains(w.Body.String(), `"data":"abc"`) {
+		t.Fatalf("handler should be overridden: %s", w.Body.String())
+	}
+	if w := serve(e, http.MethodDelete, "/api/items/abc", ""); w.Code != http.StatusForbidden {
+		t.Fatalf("route middleware should deny: %d", w.Code)
+	}
+	if w := serve(e, http.MethodPost, "/api/items/batch", "[]"); w.Code != http.StatusNotFound {
+		t.Fatalf("disabled route should not be registered: %d", w.Code)
+	}
+}
+
+func TestRegister_ContextPathSegments(t *testing.T) {
+	old := config.C.General.ContextPath
+	config.C.General.ContextPath = "/api"
+	defer func() { config.C.General.ContextPath = old }()
+
+	gin.SetMode(gin.TestMode)
+	api := NewApi[crudItem, PageParams, crudItemForm](newCrudBiz(t))
+	e := gin.New()
+	cases := map[string]string{
+		"/api":    "/api/a",
+		"/api/v1": "/api/v1/b",
+		"/apiv2":  "/apiv2/api/c",
+	}
+	for base, expected := range cases {
+		routes := Register(e.Group(base), "/"+expected[len(expected)-1:], api)
+		if routes[0].Path != expected {
+			t.Fatalf("group %s: expected %s, got %s", base, expected, routes[0].Path)
+		}
+	}
+}
+
+func TestRegister_OpenAPI(t *testing.T) {
+	newCrudEngine(t)
+
+	doc := openapi.Default.Document()
+	item := doc.Paths["/items/{id}"]
+	if item == nil || (*item)["patch"] == nil || (*item)["put"].Parameters[1].Name != "If-Match" {
+		t.Fatalf("crud routes should be described: %+v", doc.Paths)
+	}
+	if doc.Components.Schemas["crudItem"] == nil || doc.Components.Schemas["crudItemForm"] == nil {
+		t.Fatalf("T/F should be described: %v", doc.Components.Schemas)
+	}
+	query := (*doc.Paths["/items"])["get"]
+	if len(query.Parameters) != 2 || query.Parameters[0].Name != "page_num" {
+		t.Fatalf("D should be described as query parameters: %+v", query.Parameters)
+	}
+}
+
+func TestCrudApi_LocalizedNotFound(t *testing.T) {
+	e, _ := newCrudEngine(t)
+
+	if w := serve(e, http.MethodGet, "/items/missing", ""); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), `"message":"数据不存在"`) {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodDelete, "/items/batch", strings.NewReader(`{"ids":["missing"]}`))
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	e.ServeHTTP(w, req)
+	if !strings.Contains(w.Body.String(), `"message":"Record not found"`) {
+		t.Fatalf("batch errors should be localized: %s", w.Body.String())
+	}
+}
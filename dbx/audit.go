@@ -0,0 +1,92 @@
+package dbx
+
+import (
+	"reflect"
+	"slices"
+
+	"github.com/puras/mog/contextx"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// 审计字段，对应 model.BaseModel。
+const (
+	CreatedByColumn = "created_by"
+	UpdatedByColumn = "updated_by"
+)
+
+const auditCallback = "mog:audit"
+
+// AuditPlugin 从 contextx.FromUserId 自动填充审计字段：
+//   - 创建时填充 created_by/updated_by（已显式赋值的保持不变）；
+//   - 更新时填充 updated_by，UpdateColumn(s) 与 gorm 的 updated_at 一样不处理；
+//   - 软删除时的 deleted_by 由 SoftDeletePlugin 填充。
+//
+// context 中没有用户时不做任何处理。
+type AuditPlugin struct{}
+
+func (p *AuditPlugin) Name() string {
+	return auditCallback
+}
+
+func (p *AuditPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register(auditCallback, auditCreate); err != nil {
+		return err
+	}
+	return cb.Update().Before("gorm:update").Register(auditCallback, auditUpdate)
+}
+
+func auditUserOf(db *gorm.DB) (string, bool) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.SQL.Len() > 0 {
+		return "", false
+	}
+	userId := contextx.FromUserId(stmt.Context)
+	return userId, userId != ""
+}
+
+func auditCreate(db *gorm.DB) {
+	userId, ok := auditUserOf(db)
+	if !ok {
+		return
+	}
+	for _, name := range []string{CreatedByColumn, UpdatedByColumn} {
+		if field := db.Statement.Schema.LookUpField(name); field != nil {
+			fillZeroField(db, field, db.Statement.ReflectValue, userId)
+		}
+	}
+}
+
+func auditUpdate(db *gorm.DB) {
+	userId, ok := auditUserOf(db)
+	if !ok || db.Statement.SkipHooks {
+		return
+	}
+	stmt := db.Statement
+	if stmt.Schema.LookUpField(UpdatedByColumn) == nil {
+		return
+	}
+	stmt.SetColumn(UpdatedByColumn, userId, true)
+	// 与 updated_at 一样，Select 指定了部分字段时也要写入 updated_by
+	if len(stmt.Selects) > 0 && !slices.Contains(stmt.Selects, "*") && !slices.Contains(stmt.Selects, UpdatedByColumn) {
+		stmt.Selects = append(stmt.Selects, UpdatedByColumn)
+	}
+}
+
+// fillZeroField 为结构体（或结构体切片）中值为空的字段赋值。
+func fillZeroField(db *gorm.DB, field *schema.Field, rv reflect.Value, value any) {
+	ctx := db.Statement.Context
+	rv = reflect.Indirect(rv)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			fillZeroField(db, field, rv.Index(i), value)
+		}
+	case reflect.Struct:
+		if _, zero := field.ValueOf(ctx, rv); zero {
+			_ = db.AddError(field.Set(ctx, rv, value))
+		}
+	}
+}
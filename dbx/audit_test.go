@@ -0,0 +1,56 @@
+package dbx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/puras/mog/contextx"
+)
+
+func TestAudit_FillCreatedAndUpdatedBy(t *testing.T) {
+	db := newSoftDeleteDB(t)
+	ctx := contextx.NewUserId(context.Background(), "creator")
+
+	item := &softItem{Name: "audited"}
+	item.DefaultCreated()
+	if err := GetDB(ctx, db).Create(item).Error; err != nil {
+		t.Fatal(err)
+	}
+	if item.CreatedBy != "creator" || item.UpdatedBy != "creator" {
+		t.Fatalf("audit columns not filled on create: %+v", item.BaseModel)
+	}
+
+	ctx = contextx.NewUserId(context.Background(), "editor")
+	item.Name = "edited"
+	if err := GetDB(ctx, db).Model(&softItem{}).Where("id=?", item.ID).Select("*").Omit("created_at").Updates(item).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := GetDB(ctx, db).Model(&softItem{}).Where("id=?", item.ID).Updates(map[string]any{"name": "again"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var saved softItem
+	if err := db.Where("id=?", item.ID).First(&saved).Error; err != nil {
+		t.Fatal(err)
+	}
+	if saved.CreatedBy != "creator" || saved.UpdatedBy != "editor" || saved.Name != "again" {
+		t.Fatalf("unexpected audit columns after update: %+v", saved.BaseModel)
+	}
+}
+
+func TestAudit_FillUpdatedByWithSelect(t *testing.T) {
+	db := newSoftDeleteDB(t)
+	ctx := contextx.NewUserId(context.Background(), "editor")
+
+	item := &softItem{Name: "c"}
+	if err := GetDB(ctx, db).Model(&softItem{}).Where("name=?", "a").Select("name").Updates(item).Error; err != nil {
+		t.Fatal(err)
+	}
+	var saved softItem
+	if err := db.Where("name=?", "c").First(&saved).Error; err != nil {
+		t.Fatal(err)
+	}
+	if saved.UpdatedBy != "editor" {
+		t.Fatalf("updated_by should be written with Select, got %q", saved.UpdatedBy)
+	}
+}
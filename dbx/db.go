@@ -39,15 +39,17 @@ func InitDB(ctx context.Context) (*gorm.DB, func(), error) {
 	}
 
 	db, err := NewDB(Config{
-		Debug:        cfg.Debug,
-		DBType:       cfg.Type,
-		DSN:          cfg.DSN,
-		MaxLifetime:  cfg.MaxLifetime,
-		MaxIdleTime:  cfg.MaxIdleTime,
-		MaxOpenConns: cfg.MaxOpenConns,
-		MaxIdleConns: cfg.MaxIdleConns,
-		TablePrefix:  cfg.TablePrefix,
-		Resolver:     resolver,
+		Debug:         cfg.Debug,
+		DBType:        cfg.Type,
+		DSN:           cfg.DSN,
+		MaxLifetime:   cfg.MaxLifetime,
+		MaxIdleTime:   cfg.MaxIdleTime,
+		MaxOpenConns:  cfg.MaxOpenConns,
+		MaxIdleConns:  cfg.MaxIdleConns,
+		TablePrefix:   cfg.TablePrefix,
+		SlowThreshold: cfg.SlowThreshold,
+		RedactParams:  cfg.RedactParams,
+		Resolver:      resolver,
 	})
 
 	if err != nil {
@@ -55,10 +57,7 @@ func InitDB(ctx context.Context) (*gorm.DB, func(), error) {
 	}
 
 	return db, func() {
-		sqlDB, err := db.DB()
-		if err != nil {
-			_ = sqlDB.Close()
-		}
+		_ = Close(db)
 	}, nil
 }
 
@@ -76,15 +75,17 @@ type ResolverConfig struct {
 }
 
 type Config struct {
-	Debug        bool
-	DBType       string
-	DSN          string
-	MaxLifetime  int
-	MaxIdleTime  int
-	MaxOpenConns int
-	MaxIdleConns int
-	TablePrefix  string
-	Resolver     []ResolverConfig
+	Debug         bool
+	DBType        string
+	DSN           string
+	MaxLifetime   int
+	MaxIdleTime   int
+	MaxOpenConns  int
+	MaxIdleConns  int
+	TablePrefix   string
+	SlowThreshold int  // milliseconds
+	RedactParams  bool // 日志中隐藏 SQL 参数
+	Resolver      []ResolverConfig
 }
 
 func NewDB(c Config) (*gorm.DB, error) {
@@ -109,19 +110,46 @@ func NewDB(c Config) (*gorm.DB, error) {
 			TablePrefix:   c.TablePrefix,
 			SingularTable: true,
 		},
-		Logger: logger.Discard,
+	}
+	logCfg := LoggerConfig{
+		LogLevel:                  logger.Warn,
+		SlowThreshold:             time.Duration(c.SlowThreshold) * time.Millisecond,
+		RedactParams:              c.RedactParams,
+		IgnoreRecordNotFoundError: true,
 	}
 	if c.Debug {
-		config.Logger = logger.Default
+		logCfg.LogLevel = logger.Info
 	}
+	config.Logger = NewLogger(logCfg)
 
 	db, err := gorm.Open(dialector, config)
 	if err != nil {
 		return nil, err
 	}
+	if err := db.Use(&TenantPlugin{}); err != nil {
+		return nil, err
+	}
+	if c.RedactParams {
+		if err := db.Use(&redactPlugin{}); err != nil {
+			return nil, err
+		}
+	}
+	if err := db.Use(&SoftDeletePlugin{}); err != nil {
+		return nil, err
+	}
+	if err := db.Use(&AuditPlugin{}); err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	pools := &poolRegistry{}
+	pools.add(RolePrimary, RolePrimary, sqlDB)
+
 	if len(c.Resolver) > 0 {
 		resolver := &dbresolver.DBResolver{}
-		for _, r := range c.Resolver {
+		for i, r := range c.Resolver {
 			cfg := dbresolver.Config{}
 
 			var open func(dsn string) gorm.Dialector
@@ -137,17 +165,29 @@ func NewDB(c Config) (*gorm.DB, error) {
 				continue
 			}
 
-			for _, replica := range r.Replicas {
+			for j, replica := range r.Replicas {
 				if dbType == "sqlite3" {
-					_ = os.MkdirAll(filepath.Dir(c.DSN), os.ModePerm)
+					_ = os.MkdirAll(filepath.Dir(replica), os.ModePerm)
 				}
-				cfg.Replicas = append(cfg.Replicas, open(replica))
+				d, pool, err := openPool(dbType, replica, open)
+				if err != nil {
+					_ = pools.close()
+					return nil, err
+				}
+				pools.add(fmt.Sprintf("resolver%d.replica%d", i, j), RoleReplica, pool)
+				cfg.Replicas = append(cfg.Replicas, d)
 			}
-			for _, source := range r.Sources {
+			for j, source := range r.Sources {
 				if dbType == "sqlite3" {
-					_ = os.MkdirAll(filepath.Dir(c.DSN), os.ModePerm)
+					_ = os.MkdirAll(filepath.Dir(source), os.ModePerm)
+				}
+				d, pool, err := openPool(dbType, source, open)
+				if err != nil {
+					_ = pools.close()
+					return nil, err
 				}
-				cfg.Sources = append(cfg.Sources, open(source))
+				pools.add(fmt.Sprintf("resolver%d.source%d", i, j), RoleSource, pool)
+				cfg.Sources = append(cfg.Sources, d)
 			}
 			tables := stringSliceToInterfaceSlice(r.Tables)
 			resolver.Register(cfg, tables...)
@@ -159,18 +199,22 @@ func NewDB(c Config) (*gorm.DB, error) {
 			SetConnMaxLifetime(time.Duration(c.MaxLifetime) * time.Second).
 			SetConnMaxIdleTime(time.Duration(c.MaxIdleTime) * time.Second)
 		if err := db.Use(resolver); err != nil {
+			_ = pools.close()
+			return nil, err
+		}
+		if err := registerMarkWrite(db); err != nil {
+			_ = pools.close()
 			return nil, err
 		}
 	}
+	if err := db.Use(pools); err != nil {
+		_ = pools.close()
+		return nil, err
+	}
 	if c.Debug {
 		db = db.Debug()
 	}
 
-	sqlDB, err := db.DB()
-	if err != nil {
-		return nil, err
-	}
-
 	sqlDB.SetMaxIdleConns(c.MaxIdleConns)
 	sqlDB.SetMaxOpenConns(c.MaxOpenConns)
 	sqlDB.SetConnMaxLifetime(time.Duration(c.MaxLifetime) * time.Second)
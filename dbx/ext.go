@@ -24,6 +24,9 @@ func LikeParameter(v string) string {
 	return "%" + v + "%"
 }
 
+// NotDeleted 手动过滤已软删除的数据。
+//
+// Deprecated: NewDB 已注册 SoftDeletePlugin 自动过滤，无需再手动调用。
 func NotDeleted(db *gorm.DB) {
 	db.Where("deleted=false")
 }
@@ -73,6 +73,8 @@ func GetDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
 
 	if tdb, ok := contextx.FromTrans(ctx); ok {
 		db = tdb
+	} else if op, ok := resolveOperation(ctx); ok {
+		db = db.Clauses(op)
 	}
 	if contextx.FromRowLock(ctx) {
 		db = db.Clauses(clause.Locking{Strength: "UPDATE"})
@@ -158,6 +160,11 @@ func FindOne(ctx context.Context, db *gorm.DB, opts QueryOptions, out any) (bool
 	return true, nil
 }
 
+func FindAll(ctx context.Context, db *gorm.DB, opts QueryOptions, out any) error {
+	db = wrapQueryOptions(db, opts)
+	return db.Find(out).Error
+}
+
 func Exists(ctx context.Context, db *gorm.DB) (bool, error) {
 	var count int64
 	result := db.Count(&count)
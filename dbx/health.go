@@ -0,0 +1,178 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 连接池角色
+const (
+	RolePrimary = "primary"
+	RoleSource  = "source"
+	RoleReplica = "replica"
+)
+
+const poolRegistryName = "mog:pools"
+
+// Pool 一个具名的底层连接池，Name 不包含 DSN，避免泄露账号信息。
+type Pool struct {
+	Name string
+	Role string
+	DB   *sql.DB
+}
+
+// PoolStats 连接池统计，对应 sql.DBStats 中常用的指标。
+type PoolStats struct {
+	MaxOpenConnections int     `json:"maxOpenConnections"`
+	OpenConnections    int     `json:"openConnections"`
+	InUse              int     `json:"inUse"`
+	Idle               int     `json:"idle"`
+	WaitCount          int64   `json:"waitCount"`
+	WaitDurationMs     float64 `json:"waitDurationMs"`
+}
+
+func newPoolStats(s sql.DBStats) PoolStats {
+	return PoolStats{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDurationMs:     float64(s.WaitDuration.Microseconds()) / 1000,
+	}
+}
+
+// PoolStatus 单个连接池的健康状态。
+type PoolStatus struct {
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	Up        bool      `json:"up"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMs float64   `json:"latencyMs"`
+	Stats     PoolStats `json:"stats"`
+}
+
+// MetricsCollector 接收连接池统计，由业务对接 Prometheus 等监控系统。
+type MetricsCollector interface {
+	CollectPoolStats(name, role string, stats sql.DBStats)
+}
+
+// poolRegistry 以 gorm 插件的形式挂在 DB 上，记录主库和所有 resolver 的连接池。
+type poolRegistry struct {
+	pools []Pool
+}
+
+func (r *poolRegistry) Name() string {
+	return poolRegistryName
+}
+
+func (r *poolRegistry) Initialize(*gorm.DB) error {
+	return nil
+}
+
+func (r *poolRegistry) add(name, role string, db *sql.DB) {
+	r.pools = append(r.pools, Pool{Name: name, Role: role, DB: db})
+}
+
+func (r *poolRegistry) close() error {
+	var errs []error
+	for _, p := range r.pools {
+		if err := p.DB.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// openPool 为 resolver 的 DSN 单独建立连接池，再以 Conn 的方式交给 dbresolver 复用，
+// 这样健康检查和统计拿到的就是真正处理请求的连接池。
+func openPool(dbType, dsn string, open func(dsn string) gorm.Dialector) (gorm.Dialector, *sql.DB, error) {
+	db, err := gorm.Open(open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return nil, nil, err
+	}
+	pool, err := db.DB()
+	if err != nil {
+		return nil, nil, err
+	}
+	switch dbType {
+	case "mysql":
+		return mysql.New(mysql.Config{DSN: dsn, Conn: pool}), pool, nil
+	case "postgres":
+		return postgres.New(postgres.Config{DSN: dsn, Conn: pool}), pool, nil
+	default:
+		return sqlite.New(sqlite.Config{DSN: dsn, Conn: pool}), pool, nil
+	}
+}
+
+// Pools 返回 DB 上的全部连接池；非 NewDB 创建的 DB 只返回主库。
+func Pools(db *gorm.DB) []Pool {
+	if r, ok := db.Config.Plugins[poolRegistryName].(*poolRegistry); ok {
+		return r.pools
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil
+	}
+	return []Pool{{Name: RolePrimary, Role: RolePrimary, DB: sqlDB}}
+}
+
+// Ping 逐个检查连接池的连通性，返回各自状态以及是否全部可用。
+func Ping(ctx context.Context, db *gorm.DB) ([]PoolStatus, bool) {
+	pools := Pools(db)
+	ret := make([]PoolStatus, len(pools))
+	healthy := len(pools) > 0
+	for i, p := range pools {
+		start := time.Now()
+		err := p.DB.PingContext(ctx)
+		ret[i] = PoolStatus{
+			Name:      p.Name,
+			Role:      p.Role,
+			Up:        err == nil,
+			LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			Stats:     newPoolStats(p.DB.Stats()),
+		}
+		if err != nil {
+			ret[i].Error = err.Error()
+			healthy = false
+		}
+	}
+	return ret, healthy
+}
+
+// PingPrimary 只检查主库连接池，供存活检查使用，resolver 的故障不影响结果。
+func PingPrimary(ctx context.Context, db *gorm.DB) error {
+	for _, p := range Pools(db) {
+		if p.Role == RolePrimary {
+			return p.DB.PingContext(ctx)
+		}
+	}
+	return errors.New("primary pool not found")
+}
+
+// CollectStats 把当前所有连接池的统计推送给 collector，可由定时任务周期调用。
+func CollectStats(db *gorm.DB, collector MetricsCollector) {
+	for _, p := range Pools(db) {
+		collector.CollectPoolStats(p.Name, p.Role, p.DB.Stats())
+	}
+}
+
+// Close 关闭 DB 上的全部连接池。
+func Close(db *gorm.DB) error {
+	if r, ok := db.Config.Plugins[poolRegistryName].(*poolRegistry); ok {
+		return r.close()
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
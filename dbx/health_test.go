@@ -0,0 +1,53 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+type statsRecorder map[string]sql.DBStats
+
+func (r statsRecorder) CollectPoolStats(name, role string, stats sql.DBStats) {
+	r[role+"/"+name] = stats
+}
+
+func TestPing_PrimaryAndReplicas(t *testing.T) {
+	db := newResolverDB(t)
+
+	statuses, ok := Ping(context.Background(), db)
+	if !ok || len(statuses) != 2 {
+		t.Fatalf("expected primary and replica up, got %+v", statuses)
+	}
+	if statuses[0].Role != RolePrimary || statuses[1].Role != RoleReplica {
+		t.Fatalf("unexpected roles: %+v", statuses)
+	}
+
+	rec := statsRecorder{}
+	CollectStats(db, rec)
+	if _, ok := rec["replica/resolver0.replica0"]; !ok || len(rec) != 2 {
+		t.Fatalf("unexpected collected stats: %+v", rec)
+	}
+
+	// 从库故障不影响主库的存活检查
+	if err := Pools(db)[1].DB.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Ping(context.Background(), db); ok {
+		t.Fatal("expected readiness to fail with replica down")
+	}
+	if err := PingPrimary(context.Background(), db); err != nil {
+		t.Fatalf("primary should stay up: %v", err)
+	}
+
+	if err := Close(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := PingPrimary(context.Background(), db); err == nil {
+		t.Fatal("expected primary down after close")
+	}
+	statuses, ok = Ping(context.Background(), db)
+	if ok || statuses[1].Up || statuses[1].Error == "" {
+		t.Fatalf("expected pools down after close, got %+v", statuses)
+	}
+}
@@ -0,0 +1,140 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/puras/mog/contextx"
+	"github.com/puras/mog/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+)
+
+// LoggerConfig GORM 日志适配器的可调参数。
+type LoggerConfig struct {
+	LogLevel                  gormlogger.LogLevel
+	SlowThreshold             time.Duration // 慢查询阈值，<=0 关闭慢查询告警
+	RedactParams              bool          // 日志中不输出 SQL 参数，只保留占位符
+	IgnoreRecordNotFoundError bool
+}
+
+// NewLogger 返回把 SQL 日志写入 mog logger 的 GORM 日志适配器。
+//
+// 行为：
+//   - 统一使用 logger.From(ctx)，自动附带 trace_id/user_id/tag/span；
+//   - 出错记 error，超过 SlowThreshold 记 warn，其余在 Info 级别下记 info；
+//   - 每条 SQL 的次数与耗时累计到 contextx.QueryStats，供访问日志汇总。
+func NewLogger(cfg LoggerConfig) gormlogger.Interface {
+	return &gormLogger{cfg: cfg}
+}
+
+type gormLogger struct {
+	cfg LoggerConfig
+}
+
+func (l *gormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	cfg := l.cfg
+	cfg.LogLevel = level
+	return &gormLogger{cfg: cfg}
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, data ...any) {
+	if l.cfg.LogLevel >= gormlogger.Info {
+		logger.From(ctx).InfoF("[SQL] "+fmt.Sprintf(msg, data...), zap.String("source", utils.FileWithLineNum()))
+	}
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, data ...any) {
+	if l.cfg.LogLevel >= gormlogger.Warn {
+		logger.From(ctx).WarnF("[SQL] "+fmt.Sprintf(msg, data...), zap.String("source", utils.FileWithLineNum()))
+	}
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, data ...any) {
+	if l.cfg.LogLevel >= gormlogger.Error {
+		logger.From(ctx).ErrorF("[SQL] "+fmt.Sprintf(msg, data...), zap.String("source", utils.FileWithLineNum()))
+	}
+}
+
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	if stats, ok := contextx.FromQueryStats(ctx); ok {
+		stats.Add(elapsed)
+	}
+	if l.cfg.LogLevel <= gormlogger.Silent {
+		return
+	}
+
+	slow := l.cfg.SlowThreshold > 0 && elapsed > l.cfg.SlowThreshold
+	failed := err != nil && !(l.cfg.IgnoreRecordNotFoundError && errors.Is(err, gorm.ErrRecordNotFound))
+	switch {
+	case failed && l.cfg.LogLevel >= gormlogger.Error:
+		logger.From(ctx).ErrorF("[SQL]", append(l.traceFields(fc, elapsed), zap.Error(err))...)
+	case slow && l.cfg.LogLevel >= gormlogger.Warn:
+		fields := append(l.traceFields(fc, elapsed), zap.Int64("slow_threshold_ms", l.cfg.SlowThreshold.Milliseconds()))
+		logger.From(ctx).WarnF("[SQL] slow query", fields...)
+	case l.cfg.LogLevel >= gormlogger.Info:
+		logger.From(ctx).InfoF("[SQL]", l.traceFields(fc, elapsed)...)
+	}
+}
+
+// ParamsFilter 实现 gorm.ParamsFilter，开启 RedactParams 时丢弃参数，SQL 中保留占位符。
+func (l *gormLogger) ParamsFilter(ctx context.Context, sql string, params ...any) (string, []any) {
+	if l.cfg.RedactParams {
+		return redactParams(ctx, sql, params...)
+	}
+	return sql, params
+}
+
+func redactParams(ctx context.Context, sql string, params ...any) (string, []any) {
+	return sql, nil
+}
+
+const redactCallback = "mog:redact_params"
+
+type redactCtx struct{}
+
+var installRecorderFilter sync.Once
+
+// redactPlugin 让开启 RedactParams 的 DB 在 Scan 中同样丢弃参数：Scan 由 gorm 全局的 Recorder 生成 SQL，
+// 不经过适配器的 ParamsFilter，这里在 Row 回调中标记 Statement.Context，全局过滤只处理带标记的语句，
+// 其他 DB 不受影响。
+type redactPlugin struct{}
+
+func (p *redactPlugin) Name() string {
+	return redactCallback
+}
+
+func (p *redactPlugin) Initialize(db *gorm.DB) error {
+	installRecorderFilter.Do(func() {
+		prev := gormlogger.RecorderParamsFilter
+		gormlogger.RecorderParamsFilter = func(ctx context.Context, sql string, params ...any) (string, []any) {
+			if redact, _ := ctx.Value(redactCtx{}).(bool); redact {
+				return redactParams(ctx, sql, params...)
+			}
+			if prev == nil {
+				return sql, params
+			}
+			return prev(ctx, sql, params...)
+		}
+	})
+	return db.Callback().Row().Before("gorm:row").Register(redactCallback, func(db *gorm.DB) {
+		db.Statement.Context = context.WithValue(db.Statement.Context, redactCtx{}, true)
+	})
+}
+
+func (l *gormLogger) traceFields(fc func() (string, int64), elapsed time.Duration) []zap.Field {
+	sql, rows := fc()
+	return []zap.Field{
+		zap.String("sql", sql),
+		zap.Int64("rows", rows),
+		zap.Float64("cost_ms", float64(elapsed.Microseconds())/1000),
+		zap.String("source", utils.FileWithLineNum()),
+	}
+}
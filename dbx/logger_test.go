@@ -0,0 +1,102 @@
+package dbx
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/puras/mog/contextx"
+	"github.com/puras/mog/logger"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestLogger_SlowQueryRedactAndStats(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger.SetGlobal(zap.New(core))
+	defer logger.SetGlobal(zap.NewNop())
+
+	db, err := NewDB(Config{
+		DBType:       "sqlite3",
+		DSN:          filepath.Join(t.TempDir(), "log.db"),
+		RedactParams: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&resolverItem{}); err != nil {
+		t.Fatal(err)
+	}
+	// 阈值设为极小值，让每条 SQL 都被视为慢查询
+	db.Logger = NewLogger(LoggerConfig{LogLevel: gormlogger.Warn, SlowThreshold: 1, RedactParams: true})
+
+	ctx := contextx.NewQueryStats(logger.NewTraceId(context.Background(), "trace-sql"))
+	logs.TakeAll()
+	if err := GetDB(ctx, db).Create(&resolverItem{ID: "1", Name: "secret-name"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	entries := logs.FilterMessage("[SQL] slow query").All()
+	if len(entries) == 0 {
+		t.Fatalf("expected slow query log, got %+v", logs.All())
+	}
+	fields := entries[0].ContextMap()
+	if fields["trace_id"] != "trace-sql" {
+		t.Fatalf("missing trace_id: %+v", fields)
+	}
+	if sql, _ := fields["sql"].(string); strings.Contains(sql, "secret-name") || !strings.Contains(sql, "?") {
+		t.Fatalf("params should be redacted: %q", sql)
+	}
+	if stats, _ := contextx.FromQueryStats(ctx); stats.Count() != 1 {
+		t.Fatalf("expected 1 query recorded, got %d", stats.Count())
+	}
+}
+
+func TestLogger_RedactScan(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger.SetGlobal(zap.New(core))
+	defer logger.SetGlobal(zap.NewNop())
+
+	newDB := func(name string, redact bool) *gorm.DB {
+		db, err := NewDB(Config{
+			DBType:       "sqlite3",
+			DSN:          filepath.Join(t.TempDir(), name),
+			Debug:        true,
+			RedactParams: redact,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.AutoMigrate(&resolverItem{}); err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+	scanSQL := func(db *gorm.DB) string {
+		logs.TakeAll()
+		var items []resolverItem
+		if err := db.Raw("SELECT * FROM resolver_item WHERE name = ?", "secret-name").Scan(&items).Error; err != nil {
+			t.Fatal(err)
+		}
+		entries := logs.FilterMessage("[SQL]").All()
+		if len(entries) == 0 {
+			t.Fatalf("expected sql log, got %+v", logs.All())
+		}
+		sql, _ := entries[len(entries)-1].ContextMap()["sql"].(string)
+		return sql
+	}
+
+	// Scan 经 gorm 的 Recorder 生成 SQL，开启 RedactParams 的 DB 同样丢弃参数，其他 DB 不受影响
+	redacted, plain := newDB("redacted.db", true), newDB("plain.db", false)
+	if sql := scanSQL(redacted); strings.Contains(sql, "secret-name") || !strings.Contains(sql, "?") {
+		t.Fatalf("params should be redacted: %q", sql)
+	}
+	if sql := scanSQL(plain); !strings.Contains(sql, "secret-name") {
+		t.Fatalf("params of other DBs should be kept: %q", sql)
+	}
+}
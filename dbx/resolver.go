@@ -0,0 +1,51 @@
+package dbx
+
+import (
+	"context"
+
+	"github.com/puras/mog/contextx"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+const markWriteCallback = "mog:mark_write"
+
+// registerMarkWrite 在写操作成功后标记请求上下文，配合 StickyPrimary 实现读己之写。
+func registerMarkWrite(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().After("gorm:create").Register(markWriteCallback, markWrite); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register(markWriteCallback, markWrite); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register(markWriteCallback, markWrite); err != nil {
+		return err
+	}
+	return cb.Raw().After("gorm:raw").Register(markWriteCallback, markWrite)
+}
+
+func markWrite(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Context == nil {
+		return
+	}
+	if sp, ok := contextx.FromStickyPrimary(db.Statement.Context); ok {
+		sp.MarkWrite()
+	}
+}
+
+// resolveOperation 根据 context 中的提示决定本次查询走主库还是从库。
+// 事务内由事务连接决定，不做任何切换。
+func resolveOperation(ctx context.Context) (dbresolver.Operation, bool) {
+	if contextx.FromPrimary(ctx) {
+		return dbresolver.Write, true
+	}
+	if sp, ok := contextx.FromStickyPrimary(ctx); ok && sp.Active() {
+		return dbresolver.Write, true
+	}
+	if contextx.FromReplica(ctx) {
+		return dbresolver.Read, true
+	}
+	return "", false
+}
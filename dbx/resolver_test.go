@@ -0,0 +1,90 @@
+package dbx
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/puras/mog/contextx"
+	"gorm.io/gorm"
+)
+
+type resolverItem struct {
+	ID   string `gorm:"primary_key;size:64"`
+	Name string
+}
+
+// newResolverDB 打开一个主库 + 一个从库的 sqlite，两者互不同步，便于判断读取落在哪边。
+func newResolverDB(t *testing.T) *gorm.DB {
+	dir := t.TempDir()
+	primary := filepath.Join(dir, "primary.db")
+	replica := filepath.Join(dir, "replica.db")
+	for _, dsn := range []string{primary, replica} {
+		db, err := NewDB(Config{DBType: "sqlite3", DSN: dsn})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.AutoMigrate(&resolverItem{}); err != nil {
+			t.Fatal(err)
+		}
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	}
+
+	db, err := NewDB(Config{
+		DBType: "sqlite3",
+		DSN:    primary,
+		Resolver: []ResolverConfig{
+			{DBType: "sqlite3", Replicas: []string{replica}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func findResolverItem(t *testing.T, ctx context.Context, db *gorm.DB, id string) bool {
+	var item resolverItem
+	ok, err := FindOne(ctx, GetDB(ctx, db).Model(&resolverItem{}).Where("id=?", id), QueryOptions{}, &item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ok
+}
+
+func TestGetDB_PrimaryAndReplicaHints(t *testing.T) {
+	db := newResolverDB(t)
+	ctx := context.Background()
+	if err := GetDB(ctx, db).Create(&resolverItem{ID: "1", Name: "a"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if findResolverItem(t, ctx, db, "1") {
+		t.Fatalf("default read should go to replica")
+	}
+	if findResolverItem(t, contextx.NewReplica(ctx), db, "1") {
+		t.Fatalf("replica hint should read from replica")
+	}
+	if !findResolverItem(t, contextx.NewPrimary(ctx), db, "1") {
+		t.Fatalf("primary hint should read from primary")
+	}
+}
+
+func TestGetDB_StickyPrimaryAfterWrite(t *testing.T) {
+	db := newResolverDB(t)
+	ctx := contextx.NewStickyPrimary(context.Background(), 0)
+
+	if sp, _ := contextx.FromStickyPrimary(ctx); sp.Active() {
+		t.Fatalf("sticky primary should be inactive before any write")
+	}
+	if err := GetDB(ctx, db).Create(&resolverItem{ID: "1", Name: "a"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !findResolverItem(t, ctx, db, "1") {
+		t.Fatalf("read after write should stick to primary")
+	}
+	if findResolverItem(t, context.Background(), db, "1") {
+		t.Fatalf("other requests should still read from replica")
+	}
+}
@@ -0,0 +1,162 @@
+package dbx
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/puras/mog/contextx"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// 软删除字段，对应 model.DefaultModel / model.BaseModel。
+const (
+	DeletedColumn   = "deleted"
+	DeletedAtColumn = "deleted_at"
+	DeletedByColumn = "deleted_by"
+)
+
+const (
+	softDeleteCallback = "mog:soft_delete"
+	softDeleteClause   = "mog:soft_delete_enabled"
+	softDeleteSetting  = "mog:soft_delete_mode"
+
+	softDeleteWith = "with"
+	softDeleteOnly = "only"
+)
+
+// SoftDeletePlugin 为带 deleted 布尔字段的模型自动实施软删除：
+//   - 查询、更新自动追加 deleted = false，已删除数据不可见也不可修改；
+//   - Delete 改写为 UPDATE deleted/deleted_at（及 deleted_by）；
+//   - db.Unscoped() 跳过过滤并执行物理删除，WithDeleted/OnlyDeleted 调整查询范围，Restore 恢复数据。
+//
+// Delete 会在本插件内直接生成 SQL，需在 TenantPlugin 之后注册，保证租户条件先被追加。
+type SoftDeletePlugin struct{}
+
+func (p *SoftDeletePlugin) Name() string {
+	return softDeleteCallback
+}
+
+func (p *SoftDeletePlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	// 与租户隔离各自在追加条件前检查缺少条件的更新/删除
+	if err := cb.Query().Before("gorm:query").Register(softDeleteCallback, softDeleteQuery); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register(softDeleteCallback, softDeleteQuery); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register(softDeleteCallback, softDeleteUpdate); err != nil {
+		return err
+	}
+	return cb.Delete().Before("gorm:delete").Register(softDeleteCallback, softDelete)
+}
+
+// WithDeleted 查询时包含已软删除的数据，可用于 db.Scopes(dbx.WithDeleted)。
+func WithDeleted(db *gorm.DB) *gorm.DB {
+	return db.Set(softDeleteSetting, softDeleteWith)
+}
+
+// OnlyDeleted 只查询已软删除的数据，可用于 db.Scopes(dbx.OnlyDeleted)。
+func OnlyDeleted(db *gorm.DB) *gorm.DB {
+	return db.Set(softDeleteSetting, softDeleteOnly)
+}
+
+// Restore 恢复已软删除的数据，db 需指定 Model 和条件，如：
+//
+//	dbx.Restore(db.Model(new(User)).Where("id=?", id))
+func Restore(db *gorm.DB) *gorm.DB {
+	tx := OnlyDeleted(db)
+	values := map[string]any{DeletedColumn: false, DeletedAtColumn: time.Time{}}
+	if tx.Statement.Model != nil && tx.Statement.Parse(tx.Statement.Model) == nil &&
+		tx.Statement.Schema.LookUpField(DeletedByColumn) != nil {
+		values[DeletedByColumn] = ""
+	}
+	return tx.UpdateColumns(values)
+}
+
+// softDeleteOf 判断本次操作的模型是否支持软删除。
+func softDeleteOf(db *gorm.DB) (*schema.Field, bool) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.SQL.Len() > 0 || stmt.Unscoped {
+		return nil, false
+	}
+	field := stmt.Schema.LookUpField(DeletedColumn)
+	if field == nil || field.FieldType.Kind() != reflect.Bool {
+		return nil, false
+	}
+	return field, true
+}
+
+func softDeleteQuery(db *gorm.DB) {
+	if field, ok := softDeleteOf(db); ok {
+		whereSoftDelete(db.Statement, field)
+	}
+}
+
+func softDeleteUpdate(db *gorm.DB) {
+	if field, ok := softDeleteOf(db); ok && requireConditions(db) {
+		whereSoftDelete(db.Statement, field)
+	}
+}
+
+func softDelete(db *gorm.DB) {
+	field, ok := softDeleteOf(db)
+	if !ok || !requireConditions(db) {
+		return
+	}
+	stmt := db.Statement
+
+	now := db.NowFunc()
+	set := clause.Set{
+		{Column: clause.Column{Name: field.DBName}, Value: true},
+		{Column: clause.Column{Name: DeletedAtColumn}, Value: now},
+	}
+	stmt.SetColumn(field.DBName, true, true)
+	stmt.SetColumn(DeletedAtColumn, now, true)
+	if stmt.Schema.LookUpField(DeletedByColumn) != nil {
+		userId := contextx.FromUserId(stmt.Context)
+		set = append(set, clause.Assignment{Column: clause.Column{Name: DeletedByColumn}, Value: userId})
+		stmt.SetColumn(DeletedByColumn, userId, true)
+	}
+	stmt.AddClause(set)
+
+	_, queryValues := schema.GetIdentityFieldValuesMap(stmt.Context, stmt.ReflectValue, stmt.Schema.PrimaryFields)
+	column, values := schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, queryValues)
+	if len(values) > 0 {
+		stmt.AddClause(clause.Where{Exprs: []clause.Expression{clause.IN{Column: column, Values: values}}})
+	}
+	if stmt.ReflectValue.CanAddr() && stmt.Dest != stmt.Model && stmt.Model != nil {
+		_, queryValues = schema.GetIdentityFieldValuesMap(stmt.Context, reflect.ValueOf(stmt.Model), stmt.Schema.PrimaryFields)
+		column, values = schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, queryValues)
+		if len(values) > 0 {
+			stmt.AddClause(clause.Where{Exprs: []clause.Expression{clause.IN{Column: column, Values: values}}})
+		}
+	}
+
+	whereSoftDelete(stmt, field)
+	stmt.AddClauseIfNotExists(clause.Update{})
+	stmt.Build(db.Callback().Update().Clauses...)
+}
+
+func whereSoftDelete(stmt *gorm.Statement, field *schema.Field) {
+	if _, ok := stmt.Clauses[softDeleteClause]; ok {
+		return
+	}
+	stmt.Clauses[softDeleteClause] = clause.Clause{}
+
+	deleted := false
+	if v, ok := stmt.Settings.Load(softDeleteSetting); ok {
+		switch v {
+		case softDeleteWith:
+			return
+		case softDeleteOnly:
+			deleted = true
+		}
+	}
+	stmt.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: deleted},
+	}})
+}
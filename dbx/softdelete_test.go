@@ -0,0 +1,98 @@
+package dbx
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/puras/mog/contextx"
+	"github.com/puras/mog/model"
+
+	"gorm.io/gorm"
+)
+
+type softItem struct {
+	model.BaseModel
+	Name string
+}
+
+func newSoftDeleteDB(t *testing.T) *gorm.DB {
+	db, err := NewDB(Config{DBType: "sqlite3", DSN: filepath.Join(t.TempDir(), "soft.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&softItem{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b"} {
+		item := &softItem{Name: name}
+		item.DefaultCreated()
+		if err := db.Create(item).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func countSoftItems(t *testing.T, db *gorm.DB) int64 {
+	var count int64
+	if err := db.Model(&softItem{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestSoftDelete_DeleteFiltersAndRecordsUser(t *testing.T) {
+	db := newSoftDeleteDB(t)
+	ctx := contextx.NewUserId(context.Background(), "u1")
+
+	ret := GetDB(ctx, db).Where("name=?", "a").Delete(&softItem{})
+	if ret.Error != nil || ret.RowsAffected != 1 {
+		t.Fatalf("soft delete failed, rows=%d err=%v", ret.RowsAffected, ret.Error)
+	}
+	if n := countSoftItems(t, db); n != 1 {
+		t.Fatalf("deleted rows should be filtered, got %d", n)
+	}
+	if n := countSoftItems(t, db.Scopes(WithDeleted)); n != 2 {
+		t.Fatalf("WithDeleted should include deleted rows, got %d", n)
+	}
+
+	var deleted softItem
+	if err := db.Scopes(OnlyDeleted).Where("name=?", "a").First(&deleted).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !deleted.Deleted || deleted.DeletedAt.IsZero() || deleted.DeletedBy != "u1" {
+		t.Fatalf("soft delete columns not recorded: %+v", deleted.BaseModel)
+	}
+
+	ret = db.Model(&softItem{}).Where("name=?", "a").Updates(map[string]any{"name": "x"})
+	if ret.Error != nil || ret.RowsAffected != 0 {
+		t.Fatalf("deleted rows should not be updated, rows=%d err=%v", ret.RowsAffected, ret.Error)
+	}
+}
+
+func TestSoftDelete_RestoreAndUnscoped(t *testing.T) {
+	db := newSoftDeleteDB(t)
+	if err := db.Where("name=?", "a").Delete(&softItem{}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	ret := Restore(db.Model(&softItem{}).Where("name=?", "a"))
+	if ret.Error != nil || ret.RowsAffected != 1 {
+		t.Fatalf("restore failed, rows=%d err=%v", ret.RowsAffected, ret.Error)
+	}
+	if n := countSoftItems(t, db); n != 2 {
+		t.Fatalf("restored row should be visible, got %d", n)
+	}
+
+	if err := db.Unscoped().Where("name=?", "a").Delete(&softItem{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if n := countSoftItems(t, db.Unscoped()); n != 1 {
+		t.Fatalf("unscoped delete should remove the row, got %d", n)
+	}
+
+	if err := db.Delete(&softItem{}).Error; err != gorm.ErrMissingWhereClause {
+		t.Fatalf("delete without conditions should be rejected, got %v", err)
+	}
+}
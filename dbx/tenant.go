@@ -0,0 +1,163 @@
+package dbx
+
+import (
+	"reflect"
+
+	"github.com/puras/mog/contextx"
+	"github.com/puras/mog/errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// TenantColumn 租户隔离字段，对应 model.TenantModel.TenantId。
+const TenantColumn = "tenant_id"
+
+const (
+	tenantCallback = "mog:tenant"
+	tenantClause   = "mog:tenant_enabled"
+)
+
+var (
+	ErrTenantRequired = errors.Forbidden("tenant_required", "Tenant is required")
+	ErrCrossTenant    = errors.Forbidden("cross_tenant", "Cross-tenant operation is not allowed")
+)
+
+// TenantPlugin 为带 tenant_id 字段的模型自动实施租户隔离：
+//   - 查询、更新、删除自动追加 tenant_id = ?；
+//   - 创建时写入当前租户，写入其他租户的数据会被拒绝；
+//   - 更新时不允许把数据改到其他租户；
+//   - context 中没有租户时拒绝访问，可通过 contextx.NewIgnoreTenant 显式跳过。
+//
+// Raw/Exec 直接执行的 SQL 不在隔离范围内。
+type TenantPlugin struct{}
+
+func (p *TenantPlugin) Name() string {
+	return tenantCallback
+}
+
+func (p *TenantPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register(tenantCallback, tenantCreate); err != nil {
+		return err
+	}
+	if err := cb.Query().Before("gorm:query").Register(tenantCallback, tenantQuery); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register(tenantCallback, tenantQuery); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register(tenantCallback, tenantUpdate); err != nil {
+		return err
+	}
+	return cb.Delete().Before("gorm:delete").Register(tenantCallback, tenantDelete)
+}
+
+// tenantOf 判断本次操作是否需要租户隔离，返回租户字段与当前租户。
+func tenantOf(db *gorm.DB) (*schema.Field, string, bool) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.SQL.Len() > 0 {
+		return nil, "", false
+	}
+	field := stmt.Schema.LookUpField(TenantColumn)
+	if field == nil {
+		return nil, "", false
+	}
+	if contextx.FromIgnoreTenant(stmt.Context) {
+		return nil, "", false
+	}
+	tenantId := contextx.FromTenantId(stmt.Context)
+	if tenantId == "" {
+		_ = db.AddError(ErrTenantRequired)
+		return nil, "", false
+	}
+	return field, tenantId, true
+}
+
+func tenantQuery(db *gorm.DB) {
+	if field, tenantId, ok := tenantOf(db); ok {
+		whereTenant(db.Statement, field, tenantId)
+	}
+}
+
+func tenantDelete(db *gorm.DB) {
+	if field, tenantId, ok := tenantOf(db); ok && requireConditions(db) {
+		whereTenant(db.Statement, field, tenantId)
+	}
+}
+
+func tenantCreate(db *gorm.DB) {
+	if field, tenantId, ok := tenantOf(db); ok {
+		stampTenant(db, field, db.Statement.ReflectValue, tenantId)
+	}
+}
+
+func tenantUpdate(db *gorm.DB) {
+	if field, tenantId, ok := tenantOf(db); ok && requireConditions(db) {
+		// Updates(map) 时 ReflectValue 指向 Model，需要检查真正写入的 Dest
+		stampTenant(db, field, reflect.ValueOf(db.Statement.Dest), tenantId)
+		whereTenant(db.Statement, field, tenantId)
+	}
+}
+
+func whereTenant(stmt *gorm.Statement, field *schema.Field, tenantId string) {
+	// 同一个 Statement 可能被多次执行（如先 Count 再 Find），只追加一次条件
+	if _, ok := stmt.Clauses[tenantClause]; ok {
+		return
+	}
+	stmt.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenantId},
+	}})
+	stmt.Clauses[tenantClause] = clause.Clause{}
+}
+
+// requireConditions 在追加隔离条件之前检查更新/删除是否带有业务条件或主键，
+// 否则 gorm 会把我们追加的条件当成 WHERE，放行整表（整租户）操作。
+func requireConditions(db *gorm.DB) bool {
+	stmt := db.Statement
+	if db.AllowGlobalUpdate {
+		return true
+	}
+	if _, ok := stmt.Clauses["WHERE"]; ok {
+		return true
+	}
+	if stmt.Schema != nil {
+		if _, values := schema.GetIdentityFieldValuesMap(stmt.Context, stmt.ReflectValue, stmt.Schema.PrimaryFields); len(values) > 0 {
+			return true
+		}
+		if stmt.Model != nil {
+			if _, values := schema.GetIdentityFieldValuesMap(stmt.Context, reflect.ValueOf(stmt.Model), stmt.Schema.PrimaryFields); len(values) > 0 {
+				return true
+			}
+		}
+	}
+	_ = db.AddError(gorm.ErrMissingWhereClause)
+	return false
+}
+
+// stampTenant 为空的租户字段填充当前租户，已有其他租户值时拒绝。
+func stampTenant(db *gorm.DB, field *schema.Field, rv reflect.Value, tenantId string) {
+	ctx := db.Statement.Context
+	rv = reflect.Indirect(rv)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			stampTenant(db, field, rv.Index(i), tenantId)
+		}
+	case reflect.Struct:
+		if v, zero := field.ValueOf(ctx, rv); zero {
+			_ = db.AddError(field.Set(ctx, rv, tenantId))
+		} else if v != tenantId {
+			_ = db.AddError(ErrCrossTenant)
+		}
+	case reflect.Map:
+		if m, ok := rv.Interface().(map[string]any); ok {
+			for _, key := range []string{field.DBName, field.Name} {
+				if v, ok := m[key]; ok && v != tenantId {
+					_ = db.AddError(ErrCrossTenant)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package dbx
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/puras/mog/contextx"
+	"github.com/puras/mog/errors"
+	"github.com/puras/mog/model"
+
+	"gorm.io/gorm"
+)
+
+type tenantItem struct {
+	model.TenantModel
+	Name string
+}
+
+func newTenantDB(t *testing.T) *gorm.DB {
+	db, err := NewDB(Config{DBType: "sqlite3", DSN: filepath.Join(t.TempDir(), "tenant.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&tenantItem{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func createTenantItem(t *testing.T, ctx context.Context, db *gorm.DB, name string) *tenantItem {
+	item := &tenantItem{Name: name}
+	item.DefaultCreated()
+	if err := GetDB(ctx, db).Create(item).Error; err != nil {
+		t.Fatal(err)
+	}
+	return item
+}
+
+func TestTenant_CreateStampsAndQueryIsolates(t *testing.T) {
+	db := newTenantDB(t)
+	ctxA := contextx.NewTenantId(context.Background(), "A")
+	ctxB := contextx.NewTenantId(context.Background(), "B")
+
+	item := createTenantItem(t, ctxA, db, "a1")
+	createTenantItem(t, ctxB, db, "b1")
+	if item.TenantId != "A" {
+		t.Fatalf("tenant should be stamped on create, got %q", item.TenantId)
+	}
+
+	var list []*tenantItem
+	if err := GetDB(ctxA, db).Model(&tenantItem{}).Find(&list).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Name != "a1" {
+		t.Fatalf("tenant A should only see its own rows, got %+v", list)
+	}
+
+	var found tenantItem
+	ok, err := FindOne(ctxB, GetDB(ctxB, db).Model(&tenantItem{}).Where("id=?", item.ID), QueryOptions{}, &found)
+	if err != nil || ok {
+		t.Fatalf("tenant B should not find tenant A's row, ok=%v err=%v", ok, err)
+	}
+
+	var count int64
+	if err := GetDB(contextx.NewIgnoreTenant(context.Background()), db).Model(&tenantItem{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("ignore tenant should see all rows, got %d", count)
+	}
+}
+
+func TestTenant_RejectCrossTenantWrites(t *testing.T) {
+	db := newTenantDB(t)
+	ctxA := contextx.NewTenantId(context.Background(), "A")
+	ctxB := contextx.NewTenantId(context.Background(), "B")
+	item := createTenantItem(t, ctxA, db, "a1")
+
+	other := &tenantItem{Name: "forged"}
+	other.DefaultCreated()
+	other.TenantId = "A"
+	if err := GetDB(ctxB, db).Create(other).Error; !errors.Equal(err, ErrCrossTenant) {
+		t.Fatalf("create into other tenant should be rejected, got %v", err)
+	}
+
+	ret := GetDB(ctxB, db).Model(&tenantItem{}).Where("id=?", item.ID).Updates(map[string]any{"name": "hacked"})
+	if ret.Error != nil || ret.RowsAffected != 0 {
+		t.Fatalf("cross-tenant update should affect nothing, rows=%d err=%v", ret.RowsAffected, ret.Error)
+	}
+	ret = GetDB(ctxA, db).Model(&tenantItem{}).Where("id=?", item.ID).Updates(map[string]any{"tenant_id": "B"})
+	if !errors.Equal(ret.Error, ErrCrossTenant) {
+		t.Fatalf("moving row to other tenant should be rejected, got %v", ret.Error)
+	}
+	ret = GetDB(ctxB, db).Where("id=?", item.ID).Delete(&tenantItem{})
+	if ret.Error != nil || ret.RowsAffected != 0 {
+		t.Fatalf("cross-tenant delete should affect nothing, rows=%d err=%v", ret.RowsAffected, ret.Error)
+	}
+
+	var count int64
+	err := GetDB(context.Background(), db).Model(&tenantItem{}).Count(&count).Error
+	if !errors.Equal(err, ErrTenantRequired) {
+		t.Fatalf("query without tenant should be rejected, got %v", err)
+	}
+}
@@ -35,14 +35,16 @@ const (
 	DefaultRequestEntityTooLargeId = "request_entity_too_large" // 请求体过大
 	DefaultInternalServerErrorId   = "internal_server_error"    // 服务端错误
 	DefaultConflictId              = "conflict"                 // 冲突
+	DefaultPreconditionFailedId    = "precondition_failed"      // 前置条件不满足
 	DefaultRequestTimeoutId        = "request_timeout"          // 请求超时
 )
 
 type Error struct {
-	Id     string `json:"id,omitempty"`
-	Code   int32  `json:"code,omitempty"`
-	Detail string `json:"detail,omitempty"`
-	Status string `json:"status,omitempty"`
+	Id     string         `json:"id,omitempty"`
+	Code   int32          `json:"code,omitempty"`
+	Detail string         `json:"detail,omitempty"`
+	Status string         `json:"status,omitempty"`
+	Params map[string]any `json:"-"` // 本地化消息模板的参数，见 i18n 包
 }
 
 func (e *Error) Error() string {
@@ -295,6 +297,18 @@ func Conflict(id, format string, a ...any) error {
 	}
 }
 
+func PreconditionFailed(id, format string, a ...any) error {
+	if id == "" {
+		id = DefaultPreconditionFailedId
+	}
+	return &Error{
+		Id:     id,
+		Code:   http.StatusPreconditionFailed,
+		Detail: fmt.Sprintf(format, a...),
+		Status: http.StatusText(http.StatusPreconditionFailed),
+	}
+}
+
 func RequestEntityTooLarge(id, format string, a ...any) error {
 	if id == "" {
 		id = DefaultRequestEntityTooLargeId
@@ -0,0 +1,26 @@
+package i18n
+
+// 内置消息，键为 errors.Error 的 Id；resource.<name> 为 crud 资源的展示名称
+var zhBundle = Bundle{
+	"resource":            "数据",
+	"resource_not_found":  "{resource}不存在",
+	"version_conflict":    "数据已被修改，请刷新后重试",
+	"precondition_failed": "数据已被修改，请刷新后重试",
+	"batch_too_large":     "单次最多处理 {max} 条数据",
+	"tenant_required":     "缺少租户信息",
+	"cross_tenant":        "不允许跨租户操作",
+	"invalid_api_key":     "API Key 无效",
+	"resource.api_key":    "API Key",
+}
+
+var enBundle = Bundle{
+	"resource":            "Record",
+	"resource_not_found":  "{resource} not found",
+	"version_conflict":    "The data has been modified, please refresh and try again",
+	"precondition_failed": "The data has been modified, please refresh and try again",
+	"batch_too_large":     "At most {max} items can be processed at a time",
+	"tenant_required":     "Tenant is required",
+	"cross_tenant":        "Cross-tenant operation is not allowed",
+	"invalid_api_key":     "Invalid API key",
+	"resource.api_key":    "API key",
+}
@@ -0,0 +1,202 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/puras/mog/errors"
+)
+
+const (
+	LanguageZh = "zh"
+	LanguageEn = "en"
+)
+
+// Bundle 某种语言的消息，key 为 errors.Error 的 Id 等消息 ID，value 为消息模板，
+// 模板中的 {name} 由参数替换
+type Bundle map[string]string
+
+// Phrase 需要翻译的参数值，按 Id 查找，找不到时使用 Default
+type Phrase struct {
+	Id      string
+	Default string
+}
+
+// Catalog 消息目录，可实现该接口接入其他来源（如数据库、翻译平台）
+type Catalog interface {
+	// Lookup 查找消息模板，lang 不支持或没有该消息时按默认语言查找
+	Lookup(lang, id string) (string, bool)
+	// Languages 支持的语言
+	Languages() []string
+}
+
+// MapCatalog 基于内存的消息目录，可并发使用
+type MapCatalog struct {
+	mu       sync.RWMutex
+	fallback string
+	bundles  map[string]Bundle
+}
+
+func NewCatalog(fallback string) *MapCatalog {
+	return &MapCatalog{fallback: fallback, bundles: map[string]Bundle{}}
+}
+
+// Add 添加消息，已存在的消息 ID 会被覆盖
+func (c *MapCatalog) Add(lang string, bundle Bundle) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lang = strings.ToLower(lang)
+	if c.bundles[lang] == nil {
+		c.bundles[lang] = Bundle{}
+	}
+	for id, message := range bundle {
+		c.bundles[lang][id] = message
+	}
+}
+
+func (c *MapCatalog) Lookup(lang, id string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if message, ok := c.bundles[strings.ToLower(lang)][id]; ok {
+		return message, true
+	}
+	message, ok := c.bundles[c.fallback][id]
+	return message, ok
+}
+
+func (c *MapCatalog) Languages() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	langs := make([]string, 0, len(c.bundles))
+	for lang := range c.bundles {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Default 默认目录，内置中文（默认语言）和英文消息，可通过 Add 扩充或覆盖
+var Default = newDefaultCatalog()
+
+var (
+	mu      sync.RWMutex
+	catalog Catalog = Default
+)
+
+func newDefaultCatalog() *MapCatalog {
+	c := NewCatalog(LanguageZh)
+	c.Add(LanguageZh, zhBundle)
+	c.Add(LanguageEn, enBundle)
+	return c
+}
+
+// SetCatalog 替换全局使用的消息目录
+func SetCatalog(c Catalog) {
+	mu.Lock()
+	defer mu.Unlock()
+	catalog = c
+}
+
+func current() Catalog {
+	mu.RLock()
+	defer mu.RUnlock()
+	return catalog
+}
+
+// Add 向 Default 添加消息
+func Add(lang string, bundle Bundle) {
+	Default.Add(lang, bundle)
+}
+
+// Translate 按语言翻译消息，找不到消息 ID 时返回 false
+func Translate(lang, id string, params map[string]any) (string, bool) {
+	message, ok := current().Lookup(lang, id)
+	if !ok {
+		return "", false
+	}
+	if len(params) == 0 {
+		return message, true
+	}
+	pairs := make([]string, 0, len(params)*2)
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", text(lang, value))
+	}
+	return strings.NewReplacer(pairs...).Replace(message), true
+}
+
+func text(lang string, value any) string {
+	if p, ok := value.(Phrase); ok {
+		if message, ok := Translate(lang, p.Id, nil); ok {
+			return message
+		}
+		return p.Default
+	}
+	return fmt.Sprint(value)
+}
+
+// Localize 返回错误的本地化消息，目录中没有该错误 Id 时返回 Detail
+func Localize(lang string, err *errors.Error) string {
+	if err == nil {
+		return ""
+	}
+	if message, ok := Translate(lang, err.Id, err.Params); ok {
+		return message
+	}
+	return err.Detail
+}
+
+// WithParams 设置错误的消息参数，Detail 为空时按默认语言生成，err 不是 *errors.Error 时原样返回
+func WithParams(err error, params map[string]any) error {
+	e, ok := err.(*errors.Error)
+	if !ok {
+		return err
+	}
+	e.Params = params
+	if e.Detail == "" {
+		e.Detail = Localize("", e)
+	}
+	return e
+}
+
+// Match 按 Accept-Language 选择目录支持的语言，如 zh-CN,zh;q=0.9,en;q=0.8。
+// 依次尝试完整标签和主语言，都不支持时返回空字符串（使用默认语言）
+func Match(acceptLanguage string) string {
+	type tag struct {
+		lang string
+		q    float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		lang, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if lang == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			tags = append(tags, tag{lang: strings.ToLower(lang), q: q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	supported := map[string]bool{}
+	for _, lang := range current().Languages() {
+		supported[strings.ToLower(lang)] = true
+	}
+	for _, t := range tags {
+		if supported[t.lang] {
+			return t.lang
+		}
+		if base, _, ok := strings.Cut(t.lang, "-"); ok && supported[base] {
+			return base
+		}
+	}
+	return ""
+}
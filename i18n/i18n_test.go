@@ -0,0 +1,68 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/puras/mog/errors"
+)
+
+func TestMatch(t *testing.T) {
+	cases := map[string]string{
+		"":                            "",
+		"zh-CN,zh;q=0.9,en;q=0.8":     LanguageZh,
+		"en-US,en;q=0.9":              LanguageEn,
+		"fr-FR, en;q=0.5, zh;q=0.8":   LanguageZh,
+		"fr":                          "",
+		"EN":                          LanguageEn,
+		"zh;q=0, en;q=0.1":            LanguageEn,
+		"de;q=0.9, *;q=0.1, en;q=0.2": LanguageEn,
+	}
+	for header, expected := range cases {
+		if got := Match(header); got != expected {
+			t.Errorf("Match(%q) = %q, expected %q", header, got, expected)
+		}
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	Add(LanguageZh, Bundle{"resource.user": "用户"})
+	Add(LanguageEn, Bundle{"resource.user": "User"})
+
+	err := WithParams(errors.NotFound("resource_not_found", ""), map[string]any{
+		"resource": Phrase{Id: "resource.user", Default: "user"},
+	})
+	e, _ := errors.As(err)
+	if e.Detail != "用户不存在" {
+		t.Fatalf("detail should use the default language, got %q", e.Detail)
+	}
+	if got := Localize(LanguageEn, e); got != "User not found" {
+		t.Fatalf("unexpected message: %q", got)
+	}
+	if got := Localize("fr", e); got != "用户不存在" {
+		t.Fatalf("unsupported language should fall back, got %q", got)
+	}
+
+	e.Params["resource"] = Phrase{Id: "resource.order", Default: "order"}
+	if got := Localize(LanguageEn, e); got != "order not found" {
+		t.Fatalf("missing phrase should use default, got %q", got)
+	}
+
+	other, _ := errors.As(errors.BadRequest("", "invalid name"))
+	if got := Localize(LanguageEn, other); got != "invalid name" {
+		t.Fatalf("unknown id should keep detail, got %q", got)
+	}
+}
+
+func TestSetCatalog(t *testing.T) {
+	c := NewCatalog(LanguageEn)
+	c.Add(LanguageEn, Bundle{"hello": "Hello {name}"})
+	SetCatalog(c)
+	defer SetCatalog(Default)
+
+	if got, _ := Translate("zh", "hello", map[string]any{"name": "mog"}); got != "Hello mog" {
+		t.Fatalf("unexpected message: %q", got)
+	}
+	if Match("zh-CN") != "" {
+		t.Fatal("zh is not supported by the custom catalog")
+	}
+}
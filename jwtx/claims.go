@@ -0,0 +1,98 @@
+package jwtx
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims 令牌携带的声明。Id（jti）、SessionId（sid）、IssuedAt、ExpiresAt 由签发时生成；
+// Issuer、Audience 为空时使用 SetIssuer/SetAudience 配置的默认值
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  []string `json:"aud,omitempty"`
+	Id        string   `json:"jti,omitempty"`
+	SessionId string   `json:"sid,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	// Custom 自定义声明（如角色、租户、权限），签发时为任意可 JSON 序列化的值，写入 ext 声明；
+	// 解析得到的是 json.RawMessage，通过 DecodeCustom 或 CustomClaims 转换为具体类型
+	Custom any `json:"ext,omitempty"`
+}
+
+// DecodeCustom 把自定义声明解析到 v，没有自定义声明时不修改 v
+func (c *Claims) DecodeCustom(v any) error {
+	var raw []byte
+	switch custom := c.Custom.(type) {
+	case nil:
+		return nil
+	case json.RawMessage:
+		raw = custom
+	default:
+		b, err := json.Marshal(custom)
+		if err != nil {
+			return err
+		}
+		raw = b
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// CustomClaims 解析令牌并返回指定类型的自定义声明
+func CustomClaims[T any](ctx context.Context, auth Auth, token string) (*Claims, T, error) {
+	var custom T
+	c, err := auth.ParseClaims(ctx, token)
+	if err != nil {
+		return nil, custom, err
+	}
+	if err := c.DecodeCustom(&custom); err != nil {
+		return nil, custom, ErrInvalidToken
+	}
+	return c, custom, nil
+}
+
+// tokenClaims 访问令牌的 JWT 载荷
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	SessionId string          `json:"sid,omitempty"`
+	Ext       json.RawMessage `json:"ext,omitempty"`
+}
+
+func (c *tokenClaims) claims() *Claims {
+	claims := &Claims{
+		Subject:   c.Subject,
+		Issuer:    c.Issuer,
+		Audience:  c.Audience,
+		Id:        c.ID,
+		SessionId: c.SessionId,
+		IssuedAt:  unix(c.IssuedAt),
+		ExpiresAt: unix(c.ExpiresAt),
+	}
+	if len(c.Ext) > 0 {
+		claims.Custom = c.Ext
+	}
+	return claims
+}
+
+func unix(d *jwt.NumericDate) int64 {
+	if d == nil {
+		return 0
+	}
+	return d.Unix()
+}
+
+// marshalCustom 把自定义声明序列化为 ext
+func marshalCustom(custom any) (json.RawMessage, error) {
+	switch v := custom.(type) {
+	case nil:
+		return nil, nil
+	case json.RawMessage:
+		return v, nil
+	}
+	return json.Marshal(custom)
+}
@@ -0,0 +1,33 @@
+package jwtx
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// JWKSPath 公钥集合的标准路径
+const JWKSPath = "/.well-known/jwks.json"
+
+// KeySet 可公开验签公钥的 Auth，使用 HMAC 时集合为空
+type KeySet interface {
+	JWKS() JSONWebKeySet
+}
+
+func (o *jwtAuth) JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, key := range o.opts.ring.Keys() {
+		if jwk, ok := key.JWK(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// JWKSHandler 输出 JWKS 文档，供其他服务验证令牌
+func JWKSHandler(ks KeySet) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		_ = json.NewEncoder(w).Encode(ks.JWKS())
+	}
+}
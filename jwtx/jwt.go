@@ -3,39 +3,72 @@ package jwtx
 import (
 	"context"
 	"errors"
-	"github.com/golang-jwt/jwt"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/puras/mog/cachex"
 	"github.com/puras/mog/config"
+	"github.com/puras/mog/utils"
+	"os"
 	"time"
 )
 
 type Auth interface {
 	GenerateToken(ctx context.Context, subject string) (TokenInfo, error)
+	// GenerateTokenWithClaims 签发携带 iss、aud 及自定义声明的令牌，刷新后保持不变
+	GenerateTokenWithClaims(ctx context.Context, claims Claims) (TokenInfo, error)
+	// RefreshToken 用刷新令牌换取新的访问令牌和刷新令牌，旧的刷新令牌随即作废；
+	// 已作废的刷新令牌再次使用时吊销整个令牌族并返回 ErrTokenReused
+	RefreshToken(ctx context.Context, refreshToken string) (TokenInfo, error)
 	DestroyToken(ctx context.Context, token string) error
 	ParseSubject(ctx context.Context, token string) (string, error)
+	// ParseClaims 校验令牌并返回全部声明
+	ParseClaims(ctx context.Context, token string) (*Claims, error)
+	// ListSessions 列出用户的有效登录会话，Session.Id 与令牌的 sid 相同
+	ListSessions(ctx context.Context, subject string) ([]Session, error)
+	// RevokeSession 吊销用户的一个会话（如踢下某台设备），会话不属于 subject 时返回 ErrInvalidToken
+	RevokeSession(ctx context.Context, subject, id string) error
+	// RevokeSessions 吊销用户的全部会话
+	RevokeSessions(ctx context.Context, subject string) error
 	Release(ctx context.Context) error
 }
 
 const defaultKey = "CG24SDVP8OHPK395GB5G"
 
-var ErrInvalidToken = errors.New("Invalid token")
+var (
+	ErrInvalidToken = errors.New("Invalid token")
+	ErrTokenReused  = errors.New("Refresh token reused")
+)
 
 func InitAuth(ctx context.Context) (Auth, func(), error) {
 	cfg := config.C.Middleware.Auth
 	var opts []Option
 	opts = append(opts, SetExpired(cfg.Expired))
-	opts = append(opts, SetSigningKey(cfg.SigningKey))
-
-	var method jwt.SigningMethod
-	switch cfg.SigningMethod {
-	case "HS256":
-		method = jwt.SigningMethodHS256
-	case "HS384":
-		method = jwt.SigningMethodHS384
-	default:
-		method = jwt.SigningMethodHS512
+	opts = append(opts, SetRefreshExpired(cfg.RefreshExpired))
+	opts = append(opts, SetIssuer(cfg.Issuer))
+	opts = append(opts, SetAudience(cfg.Audience...))
+	opts = append(opts, SetLeeway(time.Duration(cfg.Leeway)*time.Second))
+	if cfg.LegacyUntil != "" {
+		until, err := time.Parse(time.RFC3339, cfg.LegacyUntil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid LegacyUntil: %w", err)
+		}
+		opts = append(opts, SetLegacyUntil(until))
 	}
-	opts = append(opts, SetSigningMethod(method))
+
+	key, err := loadKey(cfg.KeyId, cfg.SigningMethod, cfg.SigningKey, cfg.PrivateKeyFile, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts = append(opts, SetKey(key))
+	var retired []Key
+	for _, rk := range cfg.RetiredKeys {
+		key, err := loadKey(rk.KeyId, rk.SigningMethod, rk.SigningKey, rk.PublicKeyFile, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		retired = append(retired, key)
+	}
+	opts = append(opts, SetRetiredKeys(retired...))
 
 	var cache cachex.Cache
 	switch cfg.Store.Type {
@@ -56,19 +89,54 @@ func InitAuth(ctx context.Context) (Auth, func(), error) {
 		}, cachex.WithDelimiter(cfg.Store.Delimiter))
 	}
 
-	auth := New(NewStoreWithCache(cache), opts...)
+	var auth Auth
+	switch cfg.Mode {
+	case "opaque":
+		auth = NewOpaque(cache, opts...)
+	default:
+		auth = New(NewStoreWithCache(cache), opts...)
+	}
 	return auth, func() {
 		_ = auth.Release(ctx)
 	}, nil
 }
 
+// loadKey 按配置创建密钥：HMAC 使用 secret，非对称算法读取 PEM 文件，public 为 true 时读取的是只用于验签的公钥
+func loadKey(id, methodName, secret, pemFile string, public bool) (Key, error) {
+	method := jwt.SigningMethod(jwt.SigningMethodHS512)
+	if methodName != "" {
+		m, err := ParseSigningMethod(methodName)
+		if err != nil {
+			return Key{}, err
+		}
+		method = m
+	}
+	if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+		return NewHMACKey(id, method, []byte(secret)), nil
+	}
+
+	data, err := os.ReadFile(pemFile)
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to read key for %s: %w", method.Alg(), err)
+	}
+	if public {
+		return ParsePublicKeyPEM(id, method, data)
+	}
+	return ParsePrivateKeyPEM(id, method, data)
+}
+
 type options struct {
-	signingMethod jwt.SigningMethod
-	signingKey    []byte
-	signingKey2   []byte
-	keyFuncs      []func(*jwt.Token) (any, error)
-	expired       int
-	tokenType     string
+	signingMethod  jwt.SigningMethod
+	signingKey     []byte
+	ring           KeyRing
+	hasKey         bool
+	expired        int
+	refreshExpired int
+	tokenType      string
+	issuer         string
+	audience       []string
+	leeway         time.Duration
+	legacyUntil    time.Time
 }
 
 type Option func(*options)
@@ -85,14 +153,19 @@ func SetSigningKey(key string) Option {
 	}
 }
 
-//func SetSigningKey(key, oldKey string) Option {
-//	return func(o *options) {
-//		o.signingKey = []byte(key)
-//		if oldKey != "" && key != oldKey {
-//			o.signingKey2 = []byte(oldKey)
-//		}
-//	}
-//}
+// SetKey 设置签名密钥，支持 HMAC 及 RSA/ECDSA/Ed25519 非对称密钥，优先于 SetSigningMethod/SetSigningKey
+func SetKey(key Key) Option {
+	return func(o *options) {
+		o.ring.Current, o.hasKey = key, true
+	}
+}
+
+// SetRetiredKeys 设置已退役的密钥，只用于验证轮换前签发的令牌，并随 JWKS 发布直到移除
+func SetRetiredKeys(keys ...Key) Option {
+	return func(o *options) {
+		o.ring.Retired = keys
+	}
+}
 
 func SetExpired(expired int) Option {
 	return func(o *options) {
@@ -100,90 +173,233 @@ func SetExpired(expired int) Option {
 	}
 }
 
+// SetRefreshExpired 刷新令牌的有效期（秒），也是不活跃的登录保持的最长时间
+func SetRefreshExpired(expired int) Option {
+	return func(o *options) {
+		if expired > 0 {
+			o.refreshExpired = expired
+		}
+	}
+}
+
+// SetIssuer 签发令牌时默认的 iss，设置后只接受 iss 与之相同的令牌
+func SetIssuer(issuer string) Option {
+	return func(o *options) {
+		o.issuer = issuer
+	}
+}
+
+// SetAudience 签发令牌时默认的 aud，设置后只接受 aud 包含其中之一的令牌
+func SetAudience(audience ...string) Option {
+	return func(o *options) {
+		o.audience = audience
+	}
+}
+
+// SetLeeway 校验 exp、nbf、iat 时允许的时钟偏差
+func SetLeeway(leeway time.Duration) Option {
+	return func(o *options) {
+		o.leeway = leeway
+	}
+}
+
+// SetLegacyUntil 在此时间之前仍接受升级前签发的、不带 iss 和 aud 的令牌，
+// 一般设置为升级时间加上令牌的最长有效期
+func SetLegacyUntil(until time.Time) Option {
+	return func(o *options) {
+		o.legacyUntil = until
+	}
+}
+
 func New(store Store, opts ...Option) Auth {
 	o := options{
-		tokenType:     "Bearer",
-		expired:       7200,
-		signingMethod: jwt.SigningMethodHS512,
-		signingKey:    []byte(defaultKey),
+		tokenType:      "Bearer",
+		expired:        7200,
+		refreshExpired: 7 * 24 * 3600,
+		signingMethod:  jwt.SigningMethodHS512,
+		signingKey:     []byte(defaultKey),
 	}
 
 	for _, opt := range opts {
 		opt(&o)
 	}
 
-	o.keyFuncs = append(o.keyFuncs, func(t *jwt.Token) (any, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidToken
-		}
-		return o.signingKey, nil
-	})
+	if !o.hasKey {
+		o.ring.Current = NewHMACKey("", o.signingMethod, o.signingKey)
+	}
+
+	parserOpts := []jwt.ParserOption{jwt.WithLeeway(o.leeway), jwt.WithIssuedAt()}
+	legacyParser := jwt.NewParser(parserOpts...)
+	if o.issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(o.issuer))
+	}
+	if len(o.audience) > 0 {
+		parserOpts = append(parserOpts, jwt.WithAudience(o.audience...))
+	}
 
 	return &jwtAuth{
-		opts:  &o,
-		store: store,
+		opts:         &o,
+		store:        store,
+		parser:       jwt.NewParser(parserOpts...),
+		legacyParser: legacyParser,
 	}
 }
 
 type jwtAuth struct {
-	opts  *options
-	store Store
+	opts         *options
+	store        Store
+	opaque       *opaqueTokens // 不为空时签发不透明令牌
+	parser       *jwt.Parser
+	legacyParser *jwt.Parser // 不校验 iss、aud，用于过渡期内的旧令牌
 }
 
 func (o *jwtAuth) GenerateToken(ctx context.Context, subject string) (TokenInfo, error) {
+	return o.GenerateTokenWithClaims(ctx, Claims{Subject: subject})
+}
+
+func (o *jwtAuth) GenerateTokenWithClaims(ctx context.Context, c Claims) (TokenInfo, error) {
+	if c.Issuer == "" {
+		c.Issuer = o.opts.issuer
+	}
+	if len(c.Audience) == 0 {
+		c.Audience = o.opts.audience
+	}
+	c.SessionId = utils.NewID()
+	return o.issue(ctx, c)
+}
+
+// issue 签发属于 c.SessionId 令牌族的访问令牌和刷新令牌，并延长令牌族的有效期
+func (o *jwtAuth) issue(ctx context.Context, c Claims) (TokenInfo, error) {
 	now := time.Now()
 	expiresAt := now.Add(time.Duration(o.opts.expired) * time.Second).Unix()
+	refreshExpiresAt := now.Add(time.Duration(o.opts.refreshExpired) * time.Second).Unix()
+	ext, err := marshalCustom(c.Custom)
+	if err != nil {
+		return nil, err
+	}
 
-	token := jwt.NewWithClaims(o.opts.signingMethod, &jwt.StandardClaims{
-		IssuedAt:  now.Unix(),
-		ExpiresAt: expiresAt,
-		NotBefore: now.Unix(),
-		Subject:   subject,
-	})
-
-	tokenStr, err := token.SignedString(o.opts.signingKey)
+	claims := &tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        utils.NewID(),
+			Issuer:    c.Issuer,
+			Audience:  c.Audience,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(time.Unix(expiresAt, 0)),
+			NotBefore: jwt.NewNumericDate(now),
+			Subject:   c.Subject,
+		},
+		SessionId: c.SessionId,
+		Ext:       ext,
+	}
+	var tokenStr string
+	if o.opaque != nil {
+		tokenStr, err = o.opaque.issue(ctx, claims, time.Duration(o.opts.expired)*time.Second)
+	} else {
+		tokenStr, err = o.sign(claims)
+	}
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := randomToken()
 	if err != nil {
 		return nil, err
 	}
 	err = o.callStore(func(store Store) error {
-		return store.Set(ctx, tokenStr, time.Duration(o.opts.expired)*time.Second)
+		if err := store.SetFamily(ctx, c.SessionId, time.Duration(o.opts.refreshExpired)*time.Second); err != nil {
+			return err
+		}
+		record := RefreshRecord{
+			Subject:   c.Subject,
+			Family:    c.SessionId,
+			ExpiresAt: refreshExpiresAt,
+			Issuer:    c.Issuer,
+			Audience:  c.Audience,
+			Custom:    ext,
+		}
+		if err := store.SetRefresh(ctx, refreshToken, record); err != nil {
+			return err
+		}
+		if err := o.saveSession(ctx, store, c, now, refreshExpiresAt); err != nil {
+			return err
+		}
+		if o.opaque != nil {
+			return nil
+		}
+		return store.Set(ctx, claims.ID, time.Duration(o.opts.expired)*time.Second)
 	})
 	if err != nil {
 		return nil, err
 	}
 	tokenInfo := &tokenInfo{
-		ExpiresAt:    expiresAt,
-		TokenType:    o.opts.tokenType,
-		AccessToken:  tokenStr,
-		RefreshToken: tokenStr,
+		ExpiresAt:        expiresAt,
+		TokenType:        o.opts.tokenType,
+		AccessToken:      tokenStr,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
 	}
 	return tokenInfo, nil
 }
 
+// DestroyToken 注销访问令牌，同时吊销其令牌族，使对应的刷新令牌失效
 func (o *jwtAuth) DestroyToken(ctx context.Context, token string) error {
-	_, err := o.parseToken(token)
+	if o.opaque != nil {
+		claims, err := o.opaque.parse(ctx, token)
+		if err != nil {
+			return err
+		}
+		if err := o.opaque.revoke(ctx, token); err != nil {
+			return err
+		}
+		return o.destroySession(ctx, claims)
+	}
+
+	claims, err := o.parseToken(token)
 	if err != nil {
 		return err
 	}
-	return o.callStore(func(store Store) error {
-		//expired := time.Until(time.Unix(claims.ExpiresAt, 0))
-		//return store.Set(ctx, token, expired)
-		return store.Delete(ctx, token)
+	err = o.callStore(func(store Store) error {
+		if claims.ID == "" {
+			// 升级前签发的令牌以原文保存
+			return store.Delete(ctx, token)
+		}
+		return store.Delete(ctx, claims.ID)
 	})
+	if err != nil {
+		return err
+	}
+	return o.destroySession(ctx, claims)
+}
+
+func (o *jwtAuth) destroySession(ctx context.Context, claims *tokenClaims) error {
+	if claims.SessionId == "" {
+		return nil
+	}
+	return o.revokeSession(ctx, claims.Subject, claims.SessionId)
 }
 
 func (o *jwtAuth) ParseSubject(ctx context.Context, token string) (string, error) {
+	claims, err := o.ParseClaims(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	return claims.Subject, nil
+}
+
+func (o *jwtAuth) ParseClaims(ctx context.Context, token string) (*Claims, error) {
 	if token == "" {
-		return "", ErrInvalidToken
+		return nil, ErrInvalidToken
 	}
 
-	claims, err := o.parseToken(token)
+	claims, err := o.verify(ctx, token)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	err = o.callStore(func(store Store) error {
-		if exists, err := store.Check(ctx, token); err != nil {
+		if claims.SessionId == "" {
+			return nil
+		}
+		if exists, err := store.CheckFamily(ctx, claims.SessionId); err != nil {
 			return err
 		} else if !exists {
 			return ErrInvalidToken
@@ -191,9 +407,9 @@ func (o *jwtAuth) ParseSubject(ctx context.Context, token string) (string, error
 		return nil
 	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return claims.Subject, nil
+	return claims.claims(), nil
 }
 
 func (o *jwtAuth) Release(ctx context.Context) error {
@@ -202,23 +418,74 @@ func (o *jwtAuth) Release(ctx context.Context) error {
 	})
 }
 
-func (o *jwtAuth) parseToken(token string) (*jwt.StandardClaims, error) {
-	var (
-		tk  *jwt.Token
-		err error
-	)
+// verify 校验访问令牌，并确认令牌仍在 Store 中登记
+func (o *jwtAuth) verify(ctx context.Context, token string) (*tokenClaims, error) {
+	if o.opaque != nil {
+		return o.opaque.parse(ctx, token)
+	}
+
+	claims, err := o.parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+	err = o.callStore(func(store Store) error {
+		id := claims.ID
+		if id == "" {
+			// 升级前签发的令牌没有 jti，以原文保存
+			id = token
+		}
+		if exists, err := store.Check(ctx, id); err != nil {
+			return err
+		} else if !exists {
+			return ErrInvalidToken
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (o *jwtAuth) sign(claims *tokenClaims) (string, error) {
+	key := o.opts.ring.Current
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.Id != "" {
+		token.Header["kid"] = key.Id
+	}
+	return token.SignedString(key.Private)
+}
 
-	for _, keyFunc := range o.opts.keyFuncs {
-		tk, err = jwt.ParseWithClaims(token, &jwt.StandardClaims{}, keyFunc)
-		if err != nil || tk == nil || !tk.Valid {
-			continue
+func (o *jwtAuth) parseToken(token string) (*tokenClaims, error) {
+	for _, key := range o.opts.ring.Keys() {
+		tk, err := o.parser.ParseWithClaims(token, &tokenClaims{}, key.keyFunc)
+		if err == nil && tk.Valid {
+			return tk.Claims.(*tokenClaims), nil
+		}
+		if claims, ok := o.parseLegacy(token, key); ok {
+			return claims, nil
 		}
-		break
 	}
-	if err != nil || tk == nil || !tk.Valid {
-		return nil, ErrInvalidToken
+	return nil, ErrInvalidToken
+}
+
+// parseLegacy 过渡期内接受签名和有效期正确、但升级前签发而没有 iss 和 aud 的令牌
+func (o *jwtAuth) parseLegacy(token string, key Key) (*tokenClaims, bool) {
+	if o.opts.issuer == "" && len(o.opts.audience) == 0 {
+		return nil, false
+	}
+	if !time.Now().Before(o.opts.legacyUntil) {
+		return nil, false
+	}
+	tk, err := o.legacyParser.ParseWithClaims(token, &tokenClaims{}, key.keyFunc)
+	if err != nil || !tk.Valid {
+		return nil, false
+	}
+	claims := tk.Claims.(*tokenClaims)
+	if claims.Issuer != "" || len(claims.Audience) > 0 {
+		return nil, false
 	}
-	return tk.Claims.(*jwt.StandardClaims), nil
+	return claims, true
 }
 
 func (o *jwtAuth) callStore(fn func(Store) error) error {
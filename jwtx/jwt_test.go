@@ -0,0 +1,250 @@
+package jwtx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/puras/mog/cachex"
+)
+
+func newTestAuth(opts ...Option) Auth {
+	cache := cachex.NewMemoryCache(cachex.MemoryConfig{CleanupInterval: time.Minute})
+	return New(NewStoreWithCache(cache), opts...)
+}
+
+func TestRefreshToken_Rotation(t *testing.T) {
+	ctx := context.Background()
+	auth := newTestAuth(SetExpired(60), SetRefreshExpired(3600))
+
+	first, err := auth.GenerateToken(ctx, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.GetRefreshToken() == first.GetAccessToken() || first.GetRefreshExpiresAt() <= first.GetExpiresAt() {
+		t.Fatalf("refresh token should be distinct and live longer: %+v", first)
+	}
+
+	second, err := auth.RefreshToken(ctx, first.GetRefreshToken())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.GetRefreshToken() == first.GetRefreshToken() {
+		t.Fatal("refresh token should be rotated")
+	}
+	if subject, err := auth.ParseSubject(ctx, second.GetAccessToken()); err != nil || subject != "u1" {
+		t.Fatalf("unexpected subject %q: %v", subject, err)
+	}
+	if _, err := auth.RefreshToken(ctx, "unknown"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestRefreshToken_ReuseRevokesFamily(t *testing.T) {
+	ctx := context.Background()
+	auth := newTestAuth()
+
+	first, _ := auth.GenerateToken(ctx, "u1")
+	second, err := auth.RefreshToken(ctx, first.GetRefreshToken())
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := auth.GenerateToken(ctx, "u1")
+
+	if _, err := auth.RefreshToken(ctx, first.GetRefreshToken()); !errors.Is(err, ErrTokenReused) {
+		t.Fatalf("expected ErrTokenReused, got %v", err)
+	}
+	if _, err := auth.ParseSubject(ctx, second.GetAccessToken()); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("access tokens of the family should be revoked, got %v", err)
+	}
+	if _, err := auth.RefreshToken(ctx, second.GetRefreshToken()); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("refresh tokens of the family should be revoked, got %v", err)
+	}
+	if _, err := auth.ParseSubject(ctx, other.GetAccessToken()); err != nil {
+		t.Fatalf("other sessions should not be affected: %v", err)
+	}
+}
+
+func TestDestroyToken_RevokesRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	auth := newTestAuth()
+
+	info, _ := auth.GenerateToken(ctx, "u1")
+	if err := auth.DestroyToken(ctx, info.GetAccessToken()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.RefreshToken(ctx, info.GetRefreshToken()); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestCustomClaims(t *testing.T) {
+	type profile struct {
+		Role  string   `json:"role"`
+		Perms []string `json:"perms"`
+	}
+	ctx := context.Background()
+	auth := newTestAuth(SetIssuer("mog"), SetAudience("api"))
+
+	info, err := auth.GenerateTokenWithClaims(ctx, Claims{
+		Subject: "u1",
+		Custom:  profile{Role: "admin", Perms: []string{"user:read"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, custom, err := CustomClaims[profile](ctx, auth, info.GetAccessToken())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "u1" || claims.Issuer != "mog" || len(claims.Audience) != 1 || claims.Audience[0] != "api" {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+	if claims.Id == "" || claims.SessionId == "" || claims.ExpiresAt <= claims.IssuedAt {
+		t.Fatalf("jti, sid, iat and exp should be generated: %+v", claims)
+	}
+	if custom.Role != "admin" || len(custom.Perms) != 1 {
+		t.Fatalf("unexpected custom claims: %+v", custom)
+	}
+
+	// 刷新后的令牌保留自定义声明，jti 重新生成
+	refreshed, err := auth.RefreshToken(ctx, info.GetRefreshToken())
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, custom, err := CustomClaims[profile](ctx, auth, refreshed.GetAccessToken())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if custom.Role != "admin" || again.Audience[0] != "api" || again.SessionId != claims.SessionId || again.Id == claims.Id {
+		t.Fatalf("refresh should keep the claims: %+v %+v", again, custom)
+	}
+}
+
+// signLegacy 模拟旧版本签发的令牌：没有 kid、iss、aud、jti
+func signLegacy(t *testing.T, auth Auth, claims jwt.MapClaims) string {
+	t.Helper()
+	a := auth.(*jwtAuth)
+	key := a.opts.ring.Current
+	token, err := jwt.NewWithClaims(key.Method, claims).SignedString(key.Private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.store.Set(context.Background(), token, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestParseClaims_IssuerAudience(t *testing.T) {
+	ctx := context.Background()
+	auth := newTestAuth(SetIssuer("mog"), SetAudience("api", "admin"))
+
+	info, _ := auth.GenerateTokenWithClaims(ctx, Claims{Subject: "u1", Audience: []string{"admin"}})
+	if _, err := auth.ParseClaims(ctx, info.GetAccessToken()); err != nil {
+		t.Fatalf("tokens for one of the audiences should be accepted: %v", err)
+	}
+	for _, c := range []Claims{
+		{Subject: "u1", Issuer: "other"},
+		{Subject: "u1", Audience: []string{"web"}},
+	} {
+		info, _ := auth.GenerateTokenWithClaims(ctx, c)
+		if _, err := auth.ParseClaims(ctx, info.GetAccessToken()); !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("unexpected iss/aud should be rejected: %+v %v", c, err)
+		}
+	}
+}
+
+func TestParseClaims_Leeway(t *testing.T) {
+	ctx := context.Background()
+	claims := jwt.MapClaims{"sub": "u1", "exp": time.Now().Add(-10 * time.Second).Unix()}
+
+	strict := newTestAuth()
+	if _, err := strict.ParseClaims(ctx, signLegacy(t, strict, claims)); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expired token should be rejected, got %v", err)
+	}
+	tolerant := newTestAuth(SetLeeway(time.Minute))
+	if _, err := tolerant.ParseClaims(ctx, signLegacy(t, tolerant, claims)); err != nil {
+		t.Fatalf("token within leeway should be accepted: %v", err)
+	}
+}
+
+func TestParseClaims_LegacyTokens(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	claims := jwt.MapClaims{"sub": "u1", "iat": now.Unix(), "nbf": now.Unix(), "exp": now.Add(time.Hour).Unix()}
+
+	auth := newTestAuth(SetIssuer("mog"), SetAudience("api"), SetLegacyUntil(now.Add(time.Hour)))
+	if subject, err := auth.ParseSubject(ctx, signLegacy(t, auth, claims)); err != nil || subject != "u1" {
+		t.Fatalf("legacy tokens should be accepted during the transition: %q %v", subject, err)
+	}
+	claims["iss"] = "other"
+	if _, err := auth.ParseSubject(ctx, signLegacy(t, auth, claims)); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("tokens with a wrong iss are not legacy tokens, got %v", err)
+	}
+	delete(claims, "iss")
+
+	expired := newTestAuth(SetIssuer("mog"), SetAudience("api"), SetLegacyUntil(now.Add(-time.Second)))
+	if _, err := expired.ParseSubject(ctx, signLegacy(t, expired, claims)); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("legacy tokens should be rejected after the transition, got %v", err)
+	}
+}
+
+func TestStore_KeepsNoRawTokens(t *testing.T) {
+	ctx := context.Background()
+	cache := cachex.NewMemoryCache(cachex.MemoryConfig{CleanupInterval: time.Minute})
+	auth := New(NewStoreWithCache(cache))
+
+	info, _ := auth.GenerateToken(ctx, "u1")
+	claims, err := auth.ParseClaims(ctx, info.GetAccessToken())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ns := range []string{"jwt", "jwt_refresh"} {
+		_ = cache.Iterator(ctx, ns, func(ctx context.Context, key, value string) bool {
+			if key == info.GetAccessToken() || key == info.GetRefreshToken() {
+				t.Errorf("raw token stored in %s", ns)
+			}
+			return true
+		})
+	}
+	if ok, _ := cache.Exists(ctx, "jwt", claims.Id); !ok {
+		t.Fatal("access tokens should be stored by jti")
+	}
+
+	if err := auth.DestroyToken(ctx, info.GetAccessToken()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.ParseClaims(ctx, info.GetAccessToken()); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("destroyed token should be rejected, got %v", err)
+	}
+}
+
+func TestRefreshToken_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	auth := newTestAuth()
+	info, _ := auth.GenerateToken(ctx, "u1")
+
+	const n = 8
+	var (
+		wg        sync.WaitGroup
+		succeeded atomic.Int32
+	)
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := auth.RefreshToken(ctx, info.GetRefreshToken()); err == nil {
+				succeeded.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := succeeded.Load(); got != 1 {
+		t.Fatalf("exactly one concurrent refresh should succeed, got %d", got)
+	}
+}
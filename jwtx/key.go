@@ -0,0 +1,279 @@
+package jwtx
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key 签名/验签密钥，Id 写入令牌头部的 kid。
+// HMAC 的 Private 与 Public 均为 []byte；非对称算法的 Private 为私钥，Public 为对应的公钥
+type Key struct {
+	Id      string
+	Method  jwt.SigningMethod
+	Private any
+	Public  any
+}
+
+// NewHMACKey 创建 HS256/HS384/HS512 密钥
+func NewHMACKey(id string, method jwt.SigningMethod, secret []byte) Key {
+	return Key{Id: id, Method: method, Private: secret, Public: secret}
+}
+
+// ParseSigningMethod 按名称解析签名算法，支持 HS256/384/512、RS256/384/512、PS256/384/512、ES256/384/512、EdDSA
+func ParseSigningMethod(name string) (jwt.SigningMethod, error) {
+	method := jwt.GetSigningMethod(name)
+	if method == nil || method == jwt.SigningMethodNone {
+		return nil, fmt.Errorf("unsupported signing method: %s", name)
+	}
+	return method, nil
+}
+
+// ParsePrivateKeyPEM 从 PEM 格式的私钥创建非对称密钥，id 为空时使用公钥的 JWK Thumbprint（RFC 7638）
+func ParsePrivateKeyPEM(id string, method jwt.SigningMethod, data []byte) (Key, error) {
+	var (
+		private any
+		public  any
+		err     error
+	)
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		var k *rsa.PrivateKey
+		if k, err = jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+			private, public = k, &k.PublicKey
+		}
+	case *jwt.SigningMethodECDSA:
+		var k *ecdsa.PrivateKey
+		if k, err = jwt.ParseECPrivateKeyFromPEM(data); err == nil {
+			private, public = k, &k.PublicKey
+		}
+	case *jwt.SigningMethodEd25519:
+		var k crypto.PrivateKey
+		if k, err = jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+			private, public = k, k.(ed25519.PrivateKey).Public()
+		}
+	default:
+		return Key{}, fmt.Errorf("signing method %s does not use a PEM key", method.Alg())
+	}
+	if err != nil {
+		return Key{}, err
+	}
+
+	key := Key{Id: id, Method: method, Private: private, Public: public}
+	if key.Id == "" {
+		if key.Id, err = Thumbprint(public); err != nil {
+			return Key{}, err
+		}
+	}
+	return key, nil
+}
+
+// ParsePublicKeyPEM 从 PEM 格式的公钥创建只用于验签的密钥，id 为空时使用 JWK Thumbprint
+func ParsePublicKeyPEM(id string, method jwt.SigningMethod, data []byte) (Key, error) {
+	var (
+		public any
+		err    error
+	)
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		public, err = jwt.ParseRSAPublicKeyFromPEM(data)
+	case *jwt.SigningMethodECDSA:
+		public, err = jwt.ParseECPublicKeyFromPEM(data)
+	case *jwt.SigningMethodEd25519:
+		public, err = jwt.ParseEdPublicKeyFromPEM(data)
+	default:
+		return Key{}, fmt.Errorf("signing method %s does not use a PEM key", method.Alg())
+	}
+	if err != nil {
+		return Key{}, err
+	}
+
+	key := Key{Id: id, Method: method, Public: public}
+	if key.Id == "" {
+		if key.Id, err = Thumbprint(public); err != nil {
+			return Key{}, err
+		}
+	}
+	return key, nil
+}
+
+// keyFunc 令牌的算法与密钥一致、且 kid（如有）与密钥 Id 相同时返回验签密钥
+func (k Key) keyFunc(t *jwt.Token) (any, error) {
+	if t.Method.Alg() != k.Method.Alg() {
+		return nil, ErrInvalidToken
+	}
+	if kid, ok := t.Header["kid"].(string); ok && kid != k.Id {
+		return nil, ErrInvalidToken
+	}
+	return k.Public, nil
+}
+
+// KeyRing 当前的签名密钥及已退役、只用于验签的密钥。
+// 轮换时为新密钥设置新的 kid，把旧密钥移入 Retired，待旧令牌全部过期后再移除
+type KeyRing struct {
+	Current Key
+	Retired []Key
+}
+
+// Keys 全部密钥，当前密钥在前
+func (r KeyRing) Keys() []Key {
+	return append([]Key{r.Current}, r.Retired...)
+}
+
+// JSONWebKey 公钥的 JWK（RFC 7517）表示
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC/OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet JWKS 文档
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+var b64 = base64.RawURLEncoding
+
+// JWK 返回公钥的 JWK，HMAC 密钥不能公开，返回 false
+func (k Key) JWK() (JSONWebKey, bool) {
+	jwk, err := publicJWK(k.Public)
+	if err != nil {
+		return JSONWebKey{}, false
+	}
+	jwk.Use, jwk.Kid, jwk.Alg = "sig", k.Id, k.Method.Alg()
+	return jwk, true
+}
+
+func publicJWK(public any) (JSONWebKey, error) {
+	switch pub := public.(type) {
+	case *rsa.PublicKey:
+		return JSONWebKey{
+			Kty: "RSA",
+			N:   b64.EncodeToString(pub.N.Bytes()),
+			E:   b64.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		return JSONWebKey{
+			Kty: "EC",
+			Crv: pub.Curve.Params().Name,
+			X:   b64.EncodeToString(pub.X.FillBytes(make([]byte, size))),
+			Y:   b64.EncodeToString(pub.Y.FillBytes(make([]byte, size))),
+		}, nil
+	case ed25519.PublicKey:
+		return JSONWebKey{Kty: "OKP", Crv: "Ed25519", X: b64.EncodeToString(pub)}, nil
+	}
+	return JSONWebKey{}, errors.New("unsupported public key")
+}
+
+// PublicKey 把 JWK 转换为公钥，用于验证其他服务（如 OIDC 提供方）签发的令牌
+func (k JSONWebKey) PublicKey() (any, error) {
+	decode := func(s string) (*big.Int, error) {
+		b, err := b64.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %s", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("invalid EC key")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve: %s", k.Crv)
+		}
+		x, err := b64.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+}
+
+// Thumbprint 计算公钥的 JWK Thumbprint（RFC 7638，SHA-256）
+func Thumbprint(public any) (string, error) {
+	jwk, err := publicJWK(public)
+	if err != nil {
+		return "", err
+	}
+	// 只包含必需字段，并按字段名排序
+	var members any
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	b, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return b64.EncodeToString(sum[:]), nil
+}
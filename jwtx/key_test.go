@@ -0,0 +1,170 @@
+package jwtx
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func privateKeyPEM(t *testing.T, key any) []byte {
+	t.Helper()
+	var (
+		b   []byte
+		typ = "PRIVATE KEY"
+		err error
+	)
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		b, typ = x509.MarshalPKCS1PrivateKey(k), "RSA PRIVATE KEY"
+	case *ecdsa.PrivateKey:
+		b, err = x509.MarshalECPrivateKey(k)
+		typ = "EC PRIVATE KEY"
+	default:
+		b, err = x509.MarshalPKCS8PrivateKey(k)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: b})
+}
+
+func TestAsymmetricSigning(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	cases := []struct {
+		method string
+		key    any
+		kty    string
+	}{
+		{"RS256", rsaKey, "RSA"},
+		{"ES256", ecKey, "EC"},
+		{"EdDSA", edKey, "OKP"},
+	}
+	ctx := context.Background()
+	for _, c := range cases {
+		t.Run(c.method, func(t *testing.T) {
+			method, err := ParseSigningMethod(c.method)
+			if err != nil {
+				t.Fatal(err)
+			}
+			key, err := ParsePrivateKeyPEM("", method, privateKeyPEM(t, c.key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			auth := newTestAuth(SetKey(key))
+
+			info, err := auth.GenerateToken(ctx, "u1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if subject, err := auth.ParseSubject(ctx, info.GetAccessToken()); err != nil || subject != "u1" {
+				t.Fatalf("unexpected subject %q: %v", subject, err)
+			}
+
+			tk, _, _ := jwt.NewParser().ParseUnverified(info.GetAccessToken(), &tokenClaims{})
+			if tk.Header["kid"] != key.Id || tk.Header["alg"] != c.method {
+				t.Fatalf("unexpected header: %v", tk.Header)
+			}
+
+			w := httptest.NewRecorder()
+			JWKSHandler(auth.(KeySet))(w, httptest.NewRequest("GET", JWKSPath, nil))
+			var set JSONWebKeySet
+			if err := json.Unmarshal(w.Body.Bytes(), &set); err != nil {
+				t.Fatal(err)
+			}
+			if len(set.Keys) != 1 || set.Keys[0].Kid != key.Id || set.Keys[0].Kty != c.kty || set.Keys[0].Alg != c.method {
+				t.Fatalf("unexpected jwks: %s", w.Body.String())
+			}
+			public, err := set.Keys[0].PublicKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if thumbprint, _ := Thumbprint(public); thumbprint != key.Id {
+				t.Fatalf("jwk should convert back to the same public key")
+			}
+		})
+	}
+}
+
+func TestAsymmetricSigning_RejectsHMACToken(t *testing.T) {
+	ctx := context.Background()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	key, err := ParsePrivateKeyPEM("k1", jwt.SigningMethodRS256, privateKeyPEM(t, rsaKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := newTestAuth().(*jwtAuth).store
+	rsAuth := New(store, SetKey(key))
+	hsAuth := New(store, SetKey(NewHMACKey("k1", jwt.SigningMethodHS256, []byte("secret"))))
+
+	info, _ := hsAuth.GenerateToken(ctx, "u1")
+	if _, err := rsAuth.ParseSubject(ctx, info.GetAccessToken()); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken, got %v", err)
+	}
+	if set := hsAuth.(KeySet).JWKS(); len(set.Keys) != 0 {
+		t.Fatalf("hmac keys must not be published: %+v", set)
+	}
+}
+
+func TestThumbprint(t *testing.T) {
+	// RFC 7638 3.1
+	n := "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw"
+	b, err := b64.DecodeString(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(b), E: 65537}
+	if got, _ := Thumbprint(pub); got != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Fatalf("unexpected thumbprint %s", got)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	store := newTestAuth().(*jwtAuth).store
+	oldKey := NewHMACKey("k1", jwt.SigningMethodHS512, []byte("old"))
+	newKey := NewHMACKey("k2", jwt.SigningMethodHS512, []byte("new"))
+	legacyKey := NewHMACKey("", jwt.SigningMethodHS512, []byte("legacy"))
+
+	oldToken, _ := New(store, SetKey(oldKey)).GenerateToken(ctx, "u1")
+	legacyToken, _ := New(store, SetKey(legacyKey)).GenerateToken(ctx, "u2")
+
+	rotated := New(store, SetKey(newKey), SetRetiredKeys(oldKey, legacyKey))
+	if subject, err := rotated.ParseSubject(ctx, oldToken.GetAccessToken()); err != nil || subject != "u1" {
+		t.Fatalf("tokens signed by retired keys should be accepted: %q %v", subject, err)
+	}
+	if subject, err := rotated.ParseSubject(ctx, legacyToken.GetAccessToken()); err != nil || subject != "u2" {
+		t.Fatalf("tokens without kid should be accepted: %q %v", subject, err)
+	}
+	newToken, _ := rotated.GenerateToken(ctx, "u1")
+	tk, _, _ := jwt.NewParser().ParseUnverified(newToken.GetAccessToken(), &tokenClaims{})
+	if tk.Header["kid"] != "k2" {
+		t.Fatalf("new tokens should be signed by the current key: %v", tk.Header)
+	}
+
+	dropped := New(store, SetKey(newKey))
+	if _, err := dropped.ParseSubject(ctx, oldToken.GetAccessToken()); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("tokens of removed keys should be rejected, got %v", err)
+	}
+
+	// kid 与密钥不一致时即使密钥正确也拒绝
+	forged := New(store, SetKey(NewHMACKey("k2", jwt.SigningMethodHS512, []byte("old"))))
+	forgedToken, _ := forged.GenerateToken(ctx, "u1")
+	if _, err := rotated.ParseSubject(ctx, forgedToken.GetAccessToken()); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("kid mismatch should be rejected, got %v", err)
+	}
+}
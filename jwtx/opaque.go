@@ -0,0 +1,65 @@
+package jwtx
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/puras/mog/cachex"
+)
+
+const opaqueNS = "jwt_opaque"
+
+// NewOpaque 创建不透明令牌模式的 Auth：访问令牌是随机字符串，主体和声明保存在 cache 中，
+// 客户端无法解析令牌内容，其他服务通过 Introspect 校验。刷新令牌、会话与 JWT 模式相同
+func NewOpaque(cache cachex.Cache, opts ...Option) Auth {
+	auth := New(NewStoreWithCache(cache), opts...).(*jwtAuth)
+	auth.opaque = &opaqueTokens{cache: cache}
+	return opaqueAuth{auth}
+}
+
+// opaqueAuth 只暴露 Auth 的方法，不透明令牌不需要发布 JWKS
+type opaqueAuth struct {
+	Auth
+}
+
+// opaqueTokens 以令牌的 SHA-256 为键保存声明，键存在即令牌有效
+type opaqueTokens struct {
+	cache cachex.Cache
+}
+
+func (t *opaqueTokens) issue(ctx context.Context, claims *tokenClaims, expiration time.Duration) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	if err := t.cache.Set(ctx, opaqueNS, hashToken(token), string(b), expiration); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (t *opaqueTokens) parse(ctx context.Context, token string) (*tokenClaims, error) {
+	value, ok, err := t.cache.Get(ctx, opaqueNS, hashToken(token))
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrInvalidToken
+	}
+	claims := new(tokenClaims)
+	if err := json.Unmarshal([]byte(value), claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if claims.ExpiresAt != nil && !claims.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func (t *opaqueTokens) revoke(ctx context.Context, token string) error {
+	return t.cache.Delete(ctx, opaqueNS, hashToken(token))
+}
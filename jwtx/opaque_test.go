@@ -0,0 +1,108 @@
+package jwtx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/puras/mog/cachex"
+)
+
+func TestOpaqueAuth(t *testing.T) {
+	ctx := context.Background()
+	cache := cachex.NewMemoryCache(cachex.MemoryConfig{CleanupInterval: time.Minute})
+	auth := NewOpaque(cache, SetIssuer("mog"))
+	if _, ok := auth.(KeySet); ok {
+		t.Fatal("opaque auth should not publish jwks")
+	}
+
+	info, err := auth.GenerateTokenWithClaims(ctx, Claims{Subject: "u1", Custom: map[string]string{"role": "admin"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(info.GetAccessToken(), ".") != 0 {
+		t.Fatalf("access token should be opaque: %s", info.GetAccessToken())
+	}
+	_ = cache.Iterator(ctx, opaqueNS, func(ctx context.Context, key, value string) bool {
+		if key == info.GetAccessToken() {
+			t.Error("raw token should not be stored")
+		}
+		return true
+	})
+
+	claims, custom, err := CustomClaims[map[string]string](ctx, auth, info.GetAccessToken())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "u1" || claims.Issuer != "mog" || custom["role"] != "admin" {
+		t.Fatalf("unexpected claims: %+v %v", claims, custom)
+	}
+
+	refreshed, err := auth.RefreshToken(ctx, info.GetRefreshToken())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, custom, err := CustomClaims[map[string]string](ctx, auth, refreshed.GetAccessToken()); err != nil || custom["role"] != "admin" {
+		t.Fatalf("refresh should keep the claims: %v %v", custom, err)
+	}
+
+	if err := auth.DestroyToken(ctx, refreshed.GetAccessToken()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.ParseSubject(ctx, refreshed.GetAccessToken()); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("destroyed token should be rejected, got %v", err)
+	}
+	if _, err := auth.ParseSubject(ctx, info.GetAccessToken()); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("tokens of the destroyed session should be rejected, got %v", err)
+	}
+}
+
+func TestIntrospectHandler(t *testing.T) {
+	ctx := context.Background()
+	auth := NewOpaque(cachex.NewMemoryCache(cachex.MemoryConfig{CleanupInterval: time.Minute}), SetAudience("api"))
+	info, _ := auth.GenerateTokenWithClaims(ctx, Claims{Subject: "u1", Custom: map[string]string{"role": "admin"}})
+
+	clients := map[string]string{"orders": "s3cret"}
+	introspectAs := func(token string, setAuth func(r *http.Request)) (int, Introspection) {
+		form := url.Values{"token": {token}}
+		req := httptest.NewRequest("POST", IntrospectPath, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		setAuth(req)
+		w := httptest.NewRecorder()
+		IntrospectHandler(auth, clients)(w, req)
+		var result Introspection
+		_ = json.Unmarshal(w.Body.Bytes(), &result)
+		return w.Code, result
+	}
+	introspect := func(token string) (int, Introspection) {
+		return introspectAs(token, func(r *http.Request) { r.SetBasicAuth("orders", "s3cret") })
+	}
+
+	code, result := introspect(info.GetAccessToken())
+	if code != 200 || !result.Active || result.Subject != "u1" || result.Audience[0] != "api" || string(result.Ext) != `{"role":"admin"}` {
+		t.Fatalf("unexpected introspection: %d %+v", code, result)
+	}
+	if code, result := introspect("unknown"); code != 200 || result.Active || result.Subject != "" {
+		t.Fatalf("unknown tokens should be inactive: %d %+v", code, result)
+	}
+	if code, _ := introspect(""); code != 400 {
+		t.Fatalf("expected 400, got %d", code)
+	}
+	// 用户令牌和错误的客户端凭证都不能调用
+	for _, setAuth := range []func(r *http.Request){
+		func(r *http.Request) {},
+		func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+info.GetAccessToken()) },
+		func(r *http.Request) { r.SetBasicAuth("orders", "wrong") },
+		func(r *http.Request) { r.SetBasicAuth("unknown", "s3cret") },
+	} {
+		if code, result := introspectAs(info.GetAccessToken(), setAuth); code != 401 || result.Active {
+			t.Fatalf("expected 401, got %d %+v", code, result)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package jwtx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+)
+
+// randomToken 生成随机的不透明令牌
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (o *jwtAuth) RefreshToken(ctx context.Context, refreshToken string) (TokenInfo, error) {
+	if refreshToken == "" || o.store == nil {
+		return nil, ErrInvalidToken
+	}
+
+	// 先取走记录再判断，并发的轮换请求中只有一个能成功
+	record, ok, err := o.store.TakeRefresh(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	} else if !ok || record.ExpiresAt <= time.Now().Unix() {
+		return nil, ErrInvalidToken
+	}
+	if record.Used {
+		// 已轮换的令牌被重放，说明令牌可能泄露，吊销整个令牌族，并保留记录以识别后续重放
+		if err := o.store.SetRefresh(ctx, refreshToken, *record); err != nil {
+			return nil, err
+		}
+		if err := o.revokeSession(ctx, record.Subject, record.Family); err != nil {
+			return nil, err
+		}
+		return nil, ErrTokenReused
+	}
+	if active, err := o.store.CheckFamily(ctx, record.Family); err != nil {
+		return nil, err
+	} else if !active {
+		return nil, ErrInvalidToken
+	}
+
+	// 写回已使用的记录直到过期，用于识别重放
+	record.Used = true
+	if err := o.store.SetRefresh(ctx, refreshToken, *record); err != nil {
+		return nil, err
+	}
+	return o.issue(ctx, Claims{
+		Subject:   record.Subject,
+		Issuer:    record.Issuer,
+		Audience:  record.Audience,
+		SessionId: record.Family,
+		Custom:    record.Custom,
+	})
+}
@@ -0,0 +1,109 @@
+package jwtx
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Client 登录的客户端信息，随会话保存
+type Client struct {
+	Device    string `json:"device,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	IP        string `json:"ip,omitempty"`
+}
+
+type clientCtx struct{}
+
+// NewClientContext 记录签发或刷新令牌的客户端，保存到会话中
+func NewClientContext(ctx context.Context, client Client) context.Context {
+	return context.WithValue(ctx, clientCtx{}, client)
+}
+
+func FromClientContext(ctx context.Context) (Client, bool) {
+	client, ok := ctx.Value(clientCtx{}).(Client)
+	return client, ok
+}
+
+// saveSession 登记或延长会话
+func (o *jwtAuth) saveSession(ctx context.Context, store Store, c Claims, now time.Time, expiresAt int64) error {
+	session, ok, err := store.GetSession(ctx, c.Subject, c.SessionId)
+	if err != nil {
+		return err
+	} else if !ok {
+		session = &Session{Id: c.SessionId, Subject: c.Subject, CreatedAt: now.Unix()}
+	}
+	if client, ok := FromClientContext(ctx); ok {
+		session.Client = client
+	}
+	session.LastActiveAt, session.ExpiresAt = now.Unix(), expiresAt
+	return store.SetSession(ctx, *session)
+}
+
+// ListSessions 返回用户的有效会话，最近活跃的在前
+func (o *jwtAuth) ListSessions(ctx context.Context, subject string) ([]Session, error) {
+	if o.store == nil {
+		return nil, nil
+	}
+	sessions, err := o.store.ListSessions(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	active := sessions[:0]
+	for _, session := range sessions {
+		// 令牌族已被吊销（如刷新令牌重放）的会话一并清理
+		if ok, err := o.store.CheckFamily(ctx, session.Id); err != nil {
+			return nil, err
+		} else if !ok {
+			if err := o.store.DeleteSession(ctx, subject, session.Id); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		active = append(active, session)
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].LastActiveAt > active[j].LastActiveAt
+	})
+	return active, nil
+}
+
+// RevokeSession 吊销用户的一个会话，其访问令牌和刷新令牌随即失效。
+// id 通常来自客户端，不属于 subject 的会话返回 ErrInvalidToken
+func (o *jwtAuth) RevokeSession(ctx context.Context, subject, id string) error {
+	return o.callStore(func(store Store) error {
+		if _, ok, err := store.GetSession(ctx, subject, id); err != nil {
+			return err
+		} else if !ok {
+			return ErrInvalidToken
+		}
+		return o.revokeSession(ctx, subject, id)
+	})
+}
+
+// revokeSession 不校验归属直接吊销会话，subject 和 id 取自服务端记录或已校验的令牌
+func (o *jwtAuth) revokeSession(ctx context.Context, subject, id string) error {
+	return o.callStore(func(store Store) error {
+		if err := store.DeleteFamily(ctx, id); err != nil {
+			return err
+		}
+		return store.DeleteSession(ctx, subject, id)
+	})
+}
+
+// RevokeSessions 吊销用户的全部会话，即“退出所有设备”
+func (o *jwtAuth) RevokeSessions(ctx context.Context, subject string) error {
+	if o.store == nil {
+		return nil
+	}
+	sessions, err := o.store.ListSessions(ctx, subject)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if err := o.revokeSession(ctx, subject, session.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
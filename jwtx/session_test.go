@@ -0,0 +1,114 @@
+package jwtx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/puras/mog/cachex"
+)
+
+func TestSessions(t *testing.T) {
+	backends := map[string]func(t *testing.T) cachex.Cache{
+		"memory": func(t *testing.T) cachex.Cache {
+			return cachex.NewMemoryCache(cachex.MemoryConfig{CleanupInterval: time.Minute})
+		},
+		"badger": func(t *testing.T) cachex.Cache {
+			return cachex.NewBadgerCache(cachex.BadgerConfig{Path: t.TempDir()})
+		},
+	}
+	for name, newCache := range backends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			auth := New(NewStoreWithCache(newCache(t)))
+			defer auth.Release(ctx)
+
+			phone, _ := auth.GenerateToken(NewClientContext(ctx, Client{Device: "phone", UserAgent: "app/1.0", IP: "10.0.0.1"}), "u1")
+			laptop, _ := auth.GenerateToken(NewClientContext(ctx, Client{Device: "laptop", IP: "10.0.0.2"}), "u1")
+			other, _ := auth.GenerateToken(ctx, "u10")
+			// subject 中包含缓存分隔符时不能混入 u1 的会话
+			delimited, _ := auth.GenerateToken(ctx, "u1:x")
+
+			sessions, err := auth.ListSessions(ctx, "u1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(sessions) != 2 {
+				t.Fatalf("expected 2 sessions, got %+v", sessions)
+			}
+			claims, _ := auth.ParseClaims(ctx, phone.GetAccessToken())
+			var found *Session
+			for i := range sessions {
+				if sessions[i].Id == claims.SessionId {
+					found = &sessions[i]
+				}
+			}
+			if found == nil || found.Device != "phone" || found.UserAgent != "app/1.0" || found.IP != "10.0.0.1" || found.ExpiresAt == 0 {
+				t.Fatalf("session metadata should be kept: %+v", sessions)
+			}
+
+			// 不能吊销其他用户的会话
+			if err := auth.RevokeSession(ctx, "u2", claims.SessionId); !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("revoking another user's session should fail, got %v", err)
+			}
+			if _, err := auth.ParseSubject(ctx, phone.GetAccessToken()); err != nil {
+				t.Fatalf("session should stay active after a foreign revoke: %v", err)
+			}
+
+			// 踢下一台设备
+			if err := auth.RevokeSession(ctx, "u1", claims.SessionId); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := auth.ParseSubject(ctx, phone.GetAccessToken()); !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("revoked session should be rejected, got %v", err)
+			}
+			if _, err := auth.RefreshToken(ctx, phone.GetRefreshToken()); !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("refresh token of revoked session should be rejected, got %v", err)
+			}
+			if _, err := auth.ParseSubject(ctx, laptop.GetAccessToken()); err != nil {
+				t.Fatalf("other sessions should stay active: %v", err)
+			}
+
+			// 退出所有设备
+			if err := auth.RevokeSessions(ctx, "u1"); err != nil {
+				t.Fatal(err)
+			}
+			if sessions, _ := auth.ListSessions(ctx, "u1"); len(sessions) != 0 {
+				t.Fatalf("all sessions should be revoked: %+v", sessions)
+			}
+			if _, err := auth.ParseSubject(ctx, laptop.GetAccessToken()); !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("revoked session should be rejected, got %v", err)
+			}
+			for _, info := range []TokenInfo{other, delimited} {
+				if _, err := auth.ParseSubject(ctx, info.GetAccessToken()); err != nil {
+					t.Fatalf("sessions of other users should not be affected: %v", err)
+				}
+			}
+			if sessions, _ := auth.ListSessions(ctx, "u1:x"); len(sessions) != 1 {
+				t.Fatalf("expected 1 session for u1:x, got %+v", sessions)
+			}
+		})
+	}
+}
+
+func TestSessions_RefreshAndReuse(t *testing.T) {
+	ctx := context.Background()
+	auth := newTestAuth()
+
+	first, _ := auth.GenerateToken(NewClientContext(ctx, Client{IP: "10.0.0.1"}), "u1")
+	if _, err := auth.RefreshToken(NewClientContext(ctx, Client{IP: "10.0.0.9"}), first.GetRefreshToken()); err != nil {
+		t.Fatal(err)
+	}
+	sessions, _ := auth.ListSessions(ctx, "u1")
+	if len(sessions) != 1 || sessions[0].IP != "10.0.0.9" || sessions[0].LastActiveAt < sessions[0].CreatedAt {
+		t.Fatalf("refresh should update the session: %+v", sessions)
+	}
+
+	if _, err := auth.RefreshToken(ctx, first.GetRefreshToken()); !errors.Is(err, ErrTokenReused) {
+		t.Fatalf("expected ErrTokenReused, got %v", err)
+	}
+	if sessions, _ := auth.ListSessions(ctx, "u1"); len(sessions) != 0 {
+		t.Fatalf("reused session should be removed: %+v", sessions)
+	}
+}
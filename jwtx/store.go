@@ -2,17 +2,56 @@ package jwtx
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"github.com/puras/mog/cachex"
 	"time"
 )
 
 type Store interface {
-	Set(ctx context.Context, token string, expiration time.Duration) error
-	Delete(ctx context.Context, token string) error
-	Check(ctx context.Context, token string) (bool, error)
+	// Set 登记有效的访问令牌，id 为令牌的 jti，不保存令牌原文；Check 返回 false 的令牌视为无效
+	Set(ctx context.Context, id string, expiration time.Duration) error
+	Delete(ctx context.Context, id string) error
+	Check(ctx context.Context, id string) (bool, error)
+	// SetRefresh 保存刷新令牌，过期时间取 record.ExpiresAt，只保存令牌的 SHA-256
+	SetRefresh(ctx context.Context, token string, record RefreshRecord) error
+	// TakeRefresh 原子地取出并删除刷新令牌的记录，并发使用同一刷新令牌时只有一个能取到
+	TakeRefresh(ctx context.Context, token string) (*RefreshRecord, bool, error)
+	// SetFamily 登记令牌族（一次登录及其轮换出的全部令牌），删除后族内令牌全部失效
+	SetFamily(ctx context.Context, family string, expiration time.Duration) error
+	CheckFamily(ctx context.Context, family string) (bool, error)
+	DeleteFamily(ctx context.Context, family string) error
+	// SetSession 按 subject 索引登录会话，过期时间取 session.ExpiresAt
+	SetSession(ctx context.Context, session Session) error
+	GetSession(ctx context.Context, subject, id string) (*Session, bool, error)
+	ListSessions(ctx context.Context, subject string) ([]Session, error)
+	DeleteSession(ctx context.Context, subject, id string) error
 	Close(ctx context.Context) error
 }
 
+// RefreshRecord 刷新令牌的记录，Used 表示已被轮换，再次使用视为令牌泄露；
+// Issuer、Audience、Custom 用于刷新时签发声明相同的令牌
+type RefreshRecord struct {
+	Subject   string          `json:"subject"`
+	Family    string          `json:"family"`
+	ExpiresAt int64           `json:"expires_at"`
+	Used      bool            `json:"used,omitempty"`
+	Issuer    string          `json:"issuer,omitempty"`
+	Audience  []string        `json:"audience,omitempty"`
+	Custom    json.RawMessage `json:"custom,omitempty"`
+}
+
+// Session 一次登录，Id 即令牌族（sid），刷新令牌时延长有效期并更新 LastActiveAt
+type Session struct {
+	Id           string `json:"id"`
+	Subject      string `json:"subject"`
+	Client              // 登录时的客户端信息，刷新时更新为最近一次的
+	CreatedAt    int64  `json:"created_at"`
+	LastActiveAt int64  `json:"last_active_at"`
+	ExpiresAt    int64  `json:"expires_at"`
+}
+
 type storeOptions struct {
 	CacheNS string // default "jwt
 }
@@ -43,16 +82,118 @@ type storeImpl struct {
 	c    cachex.Cache
 }
 
-func (o *storeImpl) Set(ctx context.Context, token string, expiration time.Duration) error {
-	return o.c.Set(ctx, o.opts.CacheNS, token, "", expiration)
+func (o *storeImpl) Set(ctx context.Context, id string, expiration time.Duration) error {
+	return o.c.Set(ctx, o.opts.CacheNS, id, "", expiration)
+}
+
+func (o *storeImpl) Delete(ctx context.Context, id string) error {
+	return o.c.Delete(ctx, o.opts.CacheNS, id)
+}
+
+func (o *storeImpl) Check(ctx context.Context, id string) (bool, error) {
+	return o.c.Exists(ctx, o.opts.CacheNS, id)
+}
+
+func (o *storeImpl) SetRefresh(ctx context.Context, token string, record RefreshRecord) error {
+	expiration := time.Until(time.Unix(record.ExpiresAt, 0))
+	if expiration <= 0 {
+		return o.c.Delete(ctx, o.refreshNS(), hashToken(token))
+	}
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return o.c.Set(ctx, o.refreshNS(), hashToken(token), string(b), expiration)
+}
+
+func (o *storeImpl) TakeRefresh(ctx context.Context, token string) (*RefreshRecord, bool, error) {
+	value, ok, err := o.c.GetAndDelete(ctx, o.refreshNS(), hashToken(token))
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	record := new(RefreshRecord)
+	if err := json.Unmarshal([]byte(value), record); err != nil {
+		return nil, false, err
+	}
+	return record, true, nil
+}
+
+func (o *storeImpl) SetFamily(ctx context.Context, family string, expiration time.Duration) error {
+	return o.c.Set(ctx, o.familyNS(), family, "", expiration)
+}
+
+func (o *storeImpl) CheckFamily(ctx context.Context, family string) (bool, error) {
+	return o.c.Exists(ctx, o.familyNS(), family)
+}
+
+func (o *storeImpl) DeleteFamily(ctx context.Context, family string) error {
+	return o.c.Delete(ctx, o.familyNS(), family)
+}
+
+func (o *storeImpl) SetSession(ctx context.Context, session Session) error {
+	expiration := time.Until(time.Unix(session.ExpiresAt, 0))
+	if expiration <= 0 {
+		return o.DeleteSession(ctx, session.Subject, session.Id)
+	}
+	b, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return o.c.Set(ctx, o.sessionNS(session.Subject), session.Id, string(b), expiration)
+}
+
+func (o *storeImpl) GetSession(ctx context.Context, subject, id string) (*Session, bool, error) {
+	value, ok, err := o.c.Get(ctx, o.sessionNS(subject), id)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	session := new(Session)
+	if err := json.Unmarshal([]byte(value), session); err != nil {
+		return nil, false, err
+	}
+	return session, true, nil
+}
+
+func (o *storeImpl) ListSessions(ctx context.Context, subject string) ([]Session, error) {
+	var (
+		sessions []Session
+		err      error
+	)
+	iterErr := o.c.Iterator(ctx, o.sessionNS(subject), func(ctx context.Context, key, value string) bool {
+		var session Session
+		if err = json.Unmarshal([]byte(value), &session); err != nil {
+			return false
+		}
+		sessions = append(sessions, session)
+		return true
+	})
+	if iterErr != nil {
+		return nil, iterErr
+	}
+	return sessions, err
+}
+
+func (o *storeImpl) DeleteSession(ctx context.Context, subject, id string) error {
+	return o.c.Delete(ctx, o.sessionNS(subject), id)
+}
+
+// hashToken 令牌的 SHA-256，避免缓存中出现可直接使用的令牌
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (o *storeImpl) refreshNS() string {
+	return o.opts.CacheNS + "_refresh"
 }
 
-func (o *storeImpl) Delete(ctx context.Context, token string) error {
-	return o.c.Delete(ctx, o.opts.CacheNS, token)
+func (o *storeImpl) familyNS() string {
+	return o.opts.CacheNS + "_family"
 }
 
-func (o *storeImpl) Check(ctx context.Context, token string) (bool, error) {
-	return o.c.Exists(ctx, o.opts.CacheNS, token)
+// sessionNS 每个用户一个命名空间，subject 取哈希，避免 subject 中的分隔符让其他用户的会话落入同一前缀
+func (o *storeImpl) sessionNS(subject string) string {
+	return o.opts.CacheNS + "_session_" + hashToken(subject)
 }
 
 func (o *storeImpl) Close(ctx context.Context) error {
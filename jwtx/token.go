@@ -7,6 +7,7 @@ type TokenInfo interface {
 	GetRefreshToken() string
 	GetTokenType() string
 	GetExpiresAt() int64
+	GetRefreshExpiresAt() int64
 	EncodeToJSON() ([]byte, error)
 }
 
@@ -15,6 +16,8 @@ type tokenInfo struct {
 	RefreshToken string `json:"refresh_token"`
 	TokenType    string `json:"token_type"`
 	ExpiresAt    int64  `json:"expires_at"`
+
+	RefreshExpiresAt int64 `json:"refresh_expires_at,omitempty"`
 }
 
 func (o *tokenInfo) GetAccessToken() string {
@@ -33,6 +36,10 @@ func (o *tokenInfo) GetExpiresAt() int64 {
 	return o.ExpiresAt
 }
 
+func (o *tokenInfo) GetRefreshExpiresAt() int64 {
+	return o.RefreshExpiresAt
+}
+
 func (o *tokenInfo) EncodeToJSON() ([]byte, error) {
 	return jsoniter.Marshal(o)
 }
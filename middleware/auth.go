@@ -2,9 +2,12 @@ package middleware
 
 import (
 	"context"
+	"slices"
 
 	"github.com/gin-gonic/gin"
 	"github.com/puras/mog/contextx"
+	"github.com/puras/mog/errors"
+	"github.com/puras/mog/jwtx"
 	"github.com/puras/mog/logger"
 	"github.com/puras/mog/web"
 )
@@ -13,9 +16,12 @@ import (
 // Parser 把结果聚合到这里，再交给 Inject 写入 context；
 // 后续增加字段（如 TenantId、Permissions 等）只需扩展此结构与 Inject，
 // 不再影响 Parse 的签名和外部调用方。
+// 使用 JWT 时除 UserId（即 sub）外的字段作为自定义声明写入令牌，见 GenerateToken、JWTParser。
 type AuthInfo struct {
-	UserId string
-	Role   string
+	UserId      string   `json:"-"`
+	Role        string   `json:"role,omitempty"`
+	TenantId    string   `json:"tid,omitempty"`
+	Permissions []string `json:"perms,omitempty"`
 }
 
 // Inject 把认证信息写入 context，便于下游通过 contextx 取用。
@@ -25,9 +31,55 @@ func (a *AuthInfo) Inject(ctx context.Context) context.Context {
 	}
 	ctx = contextx.NewUserId(ctx, a.UserId)
 	ctx = contextx.NewRole(ctx, a.Role)
+	if a.TenantId != "" {
+		ctx = contextx.NewTenantId(ctx, a.TenantId)
+	}
+	if len(a.Permissions) > 0 {
+		ctx = contextx.NewPermissions(ctx, a.Permissions)
+	}
 	return ctx
 }
 
+// RequirePermissions 要求当前用户（或 API Key 的 scopes）具有全部指定的权限
+func RequirePermissions(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted := contextx.FromPermissions(c.Request.Context())
+		for _, p := range permissions {
+			if !slices.Contains(granted, p) {
+				web.ResError(c, errors.Forbidden("", "Permission %s required", p))
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// GenerateToken 签发携带认证信息的令牌
+func GenerateToken(ctx context.Context, auth jwtx.Auth, info *AuthInfo) (jwtx.TokenInfo, error) {
+	return auth.GenerateTokenWithClaims(ctx, jwtx.Claims{Subject: info.UserId, Custom: info})
+}
+
+// ClientContext 返回带有客户端信息（X-Device 请求头、User-Agent、IP）的 context，用于签发令牌时登记会话
+func ClientContext(c *gin.Context) context.Context {
+	return jwtx.NewClientContext(c.Request.Context(), jwtx.Client{
+		Device:    c.GetHeader("X-Device"),
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	})
+}
+
+// JWTParser 从请求的令牌声明直接构造 AuthInfo，可作为 AuthConfig.Parse
+func JWTParser(auth jwtx.Auth) func(*gin.Context) (*AuthInfo, error) {
+	return func(c *gin.Context) (*AuthInfo, error) {
+		claims, info, err := jwtx.CustomClaims[AuthInfo](c.Request.Context(), auth, web.GetToken(c))
+		if err != nil {
+			return nil, errors.Unauthorized("", "Invalid token")
+		}
+		info.UserId = claims.Subject
+		return &info, nil
+	}
+}
+
 type AuthConfig struct {
 	AllowedPathPrefixes []string
 	SkippedPathPrefixes []string
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/puras/mog/contextx"
 	"github.com/puras/mog/logger"
 	"github.com/puras/mog/web"
 	"go.uber.org/zap"
@@ -36,7 +37,8 @@ func Logger() gin.HandlerFunc {
 //   - 自动从 header 抓 trace_id 注入 ctx；
 //   - 把 logger.TagKeyRequest 写入 tag；
 //   - 结束统一用 logger.From(ctx).Info(...)，自动附带 trace_id/user_id/tag/span；
-//   - 请求/响应 body 仅在 <配置长度阈值 时打印，规避大对象。
+//   - 请求/响应 body 仅在 <配置长度阈值 时打印，规避大对象；
+//   - 汇总本次请求执行的 SQL 次数与耗时（db_queries/db_cost_ms）。
 func LoggerWithConfig(cfg LoggerConfig) gin.HandlerFunc {
 	if cfg.RequestHeaderKey == "" {
 		cfg.RequestHeaderKey = "X-Request-Id"
@@ -61,6 +63,10 @@ func LoggerWithConfig(cfg LoggerConfig) gin.HandlerFunc {
 			c.Request = c.Request.WithContext(ctx)
 			c.Set("request_id", v)
 		}
+
+		// —— 注入 SQL 统计 ——
+		ctx = contextx.NewQueryStats(ctx)
+		c.Request = c.Request.WithContext(ctx)
 		ctx = logger.NewTag(ctx, logger.TagKeyRequest)
 
 		start := time.Now()
@@ -76,6 +82,12 @@ func LoggerWithConfig(cfg LoggerConfig) gin.HandlerFunc {
 			zap.Int64("cost_ms", cost.Milliseconds()),
 			zap.Int("res_size", c.Writer.Size()),
 		}
+		if stats, ok := contextx.FromQueryStats(ctx); ok && stats.Count() > 0 {
+			fields = append(fields,
+				zap.Int64("db_queries", stats.Count()),
+				zap.Float64("db_cost_ms", float64(stats.Cost().Microseconds())/1000),
+			)
+		}
 
 		if c.Request.Method == "POST" || c.Request.Method == "PUT" {
 			if v, ok := c.Get(web.RequestBodyKey); ok {
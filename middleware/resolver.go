@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/puras/mog/contextx"
+)
+
+// StickyPrimaryConfig 写后粘滞主库中间件的可调参数。
+type StickyPrimaryConfig struct {
+	SkippedPathPrefixes []string
+	Window              time.Duration // 写后走主库的窗口，<=0 表示请求剩余时间
+}
+
+var DefaultStickyPrimaryConfig = StickyPrimaryConfig{}
+
+// StickyPrimary 装载默认配置的写后粘滞主库中间件。
+func StickyPrimary() gin.HandlerFunc {
+	return StickyPrimaryWithConfig(DefaultStickyPrimaryConfig)
+}
+
+// StickyPrimaryWithConfig 为每个请求安装 contextx.StickyPrimary，
+// dbx 在写库成功后标记它，之后 dbx.GetDB 的读取会被路由到主库，保证读己之写。
+func StickyPrimaryWithConfig(cfg StickyPrimaryConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if SkippedPathPrefixes(c, cfg.SkippedPathPrefixes...) {
+			c.Next()
+			return
+		}
+
+		ctx := contextx.NewStickyPrimary(c.Request.Context(), cfg.Window)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -49,6 +49,30 @@ type TenantModel struct {
 	TenantId string `json:"tenant_id" gorm:"size:16"`
 }
 
+// IVersioned 支持乐观锁的模型，crud 更新时校验并递增版本号
+type IVersioned interface {
+	GetVersion() int64
+	SetVersion(version int64)
+}
+
+// VersionModel 可与 Model 系列组合使用，为模型增加乐观锁版本号
+type VersionModel struct {
+	Version int64 `json:"version" gorm:"column:version;not null;default:1"`
+}
+
+func (self VersionModel) GetVersion() int64 {
+	return self.Version
+}
+
+func (m *VersionModel) SetVersion(version int64) {
+	m.Version = version
+}
+
+// IResource 声明模型的资源名称，crud 以 resource.<name> 为键在 i18n 目录中查找展示名称
+type IResource interface {
+	ResourceName() string
+}
+
 type IForm[T IModel] interface {
 	Validate() error
 	FillTo(item *T) error
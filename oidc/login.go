@@ -0,0 +1,213 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/puras/mog/cachex"
+	"github.com/puras/mog/errors"
+	"github.com/puras/mog/jwtx"
+	"github.com/puras/mog/middleware"
+	"github.com/puras/mog/web"
+)
+
+const (
+	stateNS         = "oidc_state"
+	DefaultStateTTL = 10 * time.Minute
+	// StateCookie 把 state 绑定到发起登录的浏览器，防止登录 CSRF
+	StateCookie = "mog_oidc_state"
+)
+
+var ErrInvalidState = errors.Unauthorized("invalid_oidc_state", "Invalid or expired login state")
+
+// MapFunc 把 ID Token 映射为本地用户的 subject，可在此查找或创建用户
+type MapFunc func(ctx context.Context, token *IdToken) (string, error)
+
+// Login 授权码 + PKCE 登录流程，回调成功后通过 Auth.GenerateToken 签发 mog 的令牌
+type Login struct {
+	Provider *Provider
+	Auth     jwtx.Auth
+	Cache    cachex.Cache  // 保存 state 对应的 code_verifier 和 nonce
+	Map      MapFunc       // 默认使用 DefaultSubject
+	StateTTL time.Duration // 默认 DefaultStateTTL
+}
+
+// DefaultSubject 以 iss|sub 作为本地 subject，避免不同提供方或本地用户的 ID 冲突
+func DefaultSubject(ctx context.Context, token *IdToken) (string, error) {
+	return token.Issuer + "|" + token.Subject, nil
+}
+
+type loginState struct {
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+}
+
+// AuthCodeURL 生成 state、nonce、code_verifier 并返回提供方的授权地址和 state，state 需要绑定到发起登录的浏览器
+func (self *Login) AuthCodeURL(ctx context.Context) (string, string, error) {
+	state, err := random()
+	if err != nil {
+		return "", "", err
+	}
+	ls := loginState{}
+	if ls.Verifier, err = random(); err != nil {
+		return "", "", err
+	}
+	if ls.Nonce, err = random(); err != nil {
+		return "", "", err
+	}
+	b, err := json.Marshal(ls)
+	if err != nil {
+		return "", "", err
+	}
+	if err := self.Cache.Set(ctx, stateNS, state, string(b), self.stateTTL()); err != nil {
+		return "", "", err
+	}
+
+	cfg := self.Provider.cfg
+	challenge := sha256.Sum256([]byte(ls.Verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {cfg.ClientId},
+		"redirect_uri":          {cfg.RedirectURL},
+		"scope":                 {strings.Join(cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {ls.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	endpoint := self.Provider.discovery.AuthorizationEndpoint
+	sep := "?"
+	if strings.Contains(endpoint, "?") {
+		sep = "&"
+	}
+	return endpoint + sep + query.Encode(), state, nil
+}
+
+// Exchange 校验 state（只能使用一次，且须与浏览器绑定的 boundState 一致），用授权码和 code_verifier 换取并校验 ID Token
+func (self *Login) Exchange(ctx context.Context, code, state, boundState string) (*IdToken, error) {
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(boundState)) != 1 {
+		return nil, ErrInvalidState
+	}
+	value, ok, err := self.Cache.GetAndDelete(ctx, stateNS, state)
+	if err != nil {
+		return nil, err
+	} else if !ok || code == "" {
+		return nil, ErrInvalidState
+	}
+	var ls loginState
+	if err := json.Unmarshal([]byte(value), &ls); err != nil {
+		return nil, ErrInvalidState
+	}
+
+	cfg := self.Provider.cfg
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {cfg.RedirectURL},
+		"code_verifier": {ls.Verifier},
+	}
+	if cfg.ClientSecret == "" {
+		// 公共客户端只携带 client_id
+		form.Set("client_id", cfg.ClientId)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, self.Provider.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(cfg.ClientId), url.QueryEscape(cfg.ClientSecret))
+	}
+	var resp struct {
+		IdToken string `json:"id_token"`
+	}
+	if err := self.Provider.do(req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.IdToken == "" {
+		return nil, ErrInvalidIdToken
+	}
+	return self.Provider.Verify(ctx, resp.IdToken, ls.Nonce)
+}
+
+// Register 挂载 GET {path}/login 和 GET {path}/callback，两者都需要加入认证中间件的 SkippedPathPrefixes
+func (self *Login) Register(group gin.IRouter, path string, handlers ...gin.HandlerFunc) {
+	g := group.Group("/"+strings.Trim(path, "/"), handlers...)
+	g.GET("/login", self.Login)
+	g.GET("/callback", self.Callback)
+}
+
+// Login 把 state 写入 Cookie 后重定向到提供方的授权页面
+func (self *Login) Login(c *gin.Context) {
+	u, state, err := self.AuthCodeURL(c.Request.Context())
+	if err != nil {
+		web.ResError(c, err)
+		return
+	}
+	self.setStateCookie(c, state, int(self.stateTTL()/time.Second))
+	c.Redirect(http.StatusFound, u)
+}
+
+// Callback 处理提供方的回调，返回 jwtx.TokenInfo
+func (self *Login) Callback(c *gin.Context) {
+	if e := c.Query("error"); e != "" {
+		web.ResError(c, errors.Unauthorized("", "OIDC login failed: %s %s", e, c.Query("error_description")))
+		return
+	}
+	ctx := c.Request.Context()
+	boundState, _ := c.Cookie(StateCookie)
+	self.setStateCookie(c, "", -1)
+	token, err := self.Exchange(ctx, c.Query("code"), c.Query("state"), boundState)
+	if err != nil {
+		web.ResError(c, err)
+		return
+	}
+
+	mapFn := self.Map
+	if mapFn == nil {
+		mapFn = DefaultSubject
+	}
+	subject, err := mapFn(ctx, token)
+	if err != nil {
+		web.ResError(c, err)
+		return
+	}
+	info, err := self.Auth.GenerateToken(middleware.ClientContext(c), subject)
+	if err != nil {
+		web.ResError(c, err)
+		return
+	}
+	web.ResSuccess(c, info)
+}
+
+func (self *Login) stateTTL() time.Duration {
+	if self.StateTTL <= 0 {
+		return DefaultStateTTL
+	}
+	return self.StateTTL
+}
+
+// setStateCookie 写入或清除（maxAge < 0）state Cookie，SameSite=Lax 保证提供方的重定向能携带
+func (self *Login) setStateCookie(c *gin.Context, state string, maxAge int) {
+	secure := c.Request.TLS != nil || strings.HasPrefix(self.Provider.cfg.RedirectURL, "https://")
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(StateCookie, state, maxAge, "/", "", secure, true)
+}
+
+func random() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,241 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/puras/mog/cachex"
+	"github.com/puras/mog/jwtx"
+)
+
+// stubProvider 本地的 OIDC 提供方，记录授权请求并在换取令牌时校验 PKCE
+type stubProvider struct {
+	*httptest.Server
+	key  *rsa.PrivateKey
+	mu   sync.Mutex
+	auth map[string]url.Values // code -> 授权请求参数
+}
+
+func newStubProvider(t *testing.T) *stubProvider {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	p := &stubProvider{key: key, auth: map[string]url.Values{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(Discovery{
+			Issuer:                p.URL,
+			AuthorizationEndpoint: p.URL + "/authorize",
+			TokenEndpoint:         p.URL + "/token",
+			JWKSURI:               p.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		jwk, _ := jwtx.Key{Id: "k1", Method: jwt.SigningMethodRS256, Public: &key.PublicKey}.JWK()
+		_ = json.NewEncoder(w).Encode(jwtx.JSONWebKeySet{Keys: []jwtx.JSONWebKey{jwk}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		p.mu.Lock()
+		params, ok := p.auth[r.PostFormValue("code")]
+		delete(p.auth, r.PostFormValue("code"))
+		p.mu.Unlock()
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if id != "client" || secret != "secret" || !ok ||
+			params.Get("code_challenge") != base64.RawURLEncoding.EncodeToString(sum[:]) ||
+			r.PostFormValue("redirect_uri") != params.Get("redirect_uri") {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		idToken := p.sign(t, jwt.MapClaims{"aud": "client", "nonce": params.Get("nonce"), "email": "alice@example.com"})
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "token_type": "Bearer", "id_token": idToken})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// authorize 模拟用户在提供方完成登录，返回授权码
+func (p *stubProvider) authorize(t *testing.T, location string) (code, state string) {
+	u, err := url.Parse(location)
+	if err != nil || !strings.HasPrefix(location, p.URL+"/authorize") {
+		t.Fatalf("unexpected authorization url: %s", location)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("nonce") == "" || q.Get("scope") != "openid profile email" {
+		t.Fatalf("unexpected authorization request: %s", location)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	code = "code-" + q.Get("state")[:8]
+	p.auth[code] = q
+	return code, q.Get("state")
+}
+
+func (p *stubProvider) sign(t *testing.T, claims jwt.MapClaims) string {
+	now := time.Now()
+	defaults := jwt.MapClaims{"iss": p.URL, "sub": "alice", "iat": now.Unix(), "exp": now.Add(time.Minute).Unix()}
+	for k, v := range defaults {
+		if _, ok := claims[k]; !ok {
+			claims[k] = v
+		}
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "k1"
+	s, err := token.SignedString(p.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func newLogin(t *testing.T, p *stubProvider) *Login {
+	provider, err := NewProvider(context.Background(), Config{
+		Issuer:       p.URL,
+		ClientId:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://app.local/oidc/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := cachex.NewMemoryCache(cachex.MemoryConfig{CleanupInterval: time.Minute})
+	return &Login{
+		Provider: provider,
+		Auth:     jwtx.New(jwtx.NewStoreWithCache(cache)),
+		Cache:    cache,
+		Map: func(ctx context.Context, token *IdToken) (string, error) {
+			return "local-" + token.Email, nil
+		},
+	}
+}
+
+// loginFlow 走完 login → authorize，返回回调地址和浏览器收到的 state Cookie
+func loginFlow(t *testing.T, e *gin.Engine, p *stubProvider) (string, *http.Cookie) {
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/oidc/login", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected redirect, got %d", w.Code)
+	}
+	var cookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == StateCookie {
+			cookie = c
+		}
+	}
+	if cookie == nil || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("unexpected state cookie: %+v", cookie)
+	}
+	code, state := p.authorize(t, w.Header().Get("Location"))
+	return "/oidc/callback?" + url.Values{"code": {code}, "state": {state}}.Encode(), cookie
+}
+
+func callback(e *gin.Engine, target string, cookie *http.Cookie) (*httptest.ResponseRecorder, string) {
+	req := httptest.NewRequest("GET", target, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	var res struct {
+		Data struct {
+			AccessToken string `json:"access_token"`
+		} `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &res)
+	return w, res.Data.AccessToken
+}
+
+func TestLogin(t *testing.T) {
+	p := newStubProvider(t)
+	login := newLogin(t, p)
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	login.Register(e, "/oidc")
+
+	target, cookie := loginFlow(t, e, p)
+	w, accessToken := callback(e, target, cookie)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+	subject, err := login.Auth.ParseSubject(context.Background(), accessToken)
+	if err != nil || subject != "local-alice@example.com" {
+		t.Fatalf("unexpected subject %q: %v", subject, err)
+	}
+
+	// state 只能使用一次
+	if w, _ := callback(e, target, cookie); w.Code != http.StatusUnauthorized {
+		t.Fatalf("replayed state should be rejected, got %d", w.Code)
+	}
+}
+
+func TestLogin_StateCookie(t *testing.T) {
+	p := newStubProvider(t)
+	login := newLogin(t, p)
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	login.Register(e, "/oidc")
+
+	// 攻击者诱导受害者浏览器访问自己的回调地址：没有或不匹配的 Cookie 都被拒绝
+	target, _ := loginFlow(t, e, p)
+	_, other := loginFlow(t, e, p)
+	if w, _ := callback(e, target, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("callback without cookie should be rejected, got %d", w.Code)
+	}
+	if w, _ := callback(e, target, other); w.Code != http.StatusUnauthorized {
+		t.Fatalf("callback with another browser's cookie should be rejected, got %d", w.Code)
+	}
+}
+
+func TestLogin_DefaultSubject(t *testing.T) {
+	p := newStubProvider(t)
+	login := newLogin(t, p)
+	login.Map = nil
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	login.Register(e, "/oidc")
+
+	target, cookie := loginFlow(t, e, p)
+	_, accessToken := callback(e, target, cookie)
+	subject, err := login.Auth.ParseSubject(context.Background(), accessToken)
+	if err != nil || subject != p.URL+"|alice" {
+		t.Fatalf("unexpected subject %q: %v", subject, err)
+	}
+}
+
+func TestProvider_Verify(t *testing.T) {
+	p := newStubProvider(t)
+	provider := newLogin(t, p).Provider
+	ctx := context.Background()
+
+	if token, err := provider.Verify(ctx, p.sign(t, jwt.MapClaims{"aud": "client", "nonce": "n"}), "n"); err != nil || token.Subject != "alice" {
+		t.Fatalf("valid id token should be accepted: %+v %v", token, err)
+	}
+
+	hmac, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iss": p.URL, "sub": "alice", "aud": "client", "nonce": "n", "exp": time.Now().Add(time.Minute).Unix()}).SignedString([]byte("secret"))
+	cases := map[string]string{
+		"nonce":    p.sign(t, jwt.MapClaims{"aud": "client", "nonce": "other"}),
+		"audience": p.sign(t, jwt.MapClaims{"aud": "other", "nonce": "n"}),
+		"azp":      p.sign(t, jwt.MapClaims{"aud": []string{"client", "other"}, "nonce": "n"}),
+		"issuer":   p.sign(t, jwt.MapClaims{"aud": "client", "nonce": "n", "iss": "https://evil.example.com"}),
+		"expired":  p.sign(t, jwt.MapClaims{"aud": "client", "nonce": "n", "exp": time.Now().Add(-time.Minute).Unix()}),
+		"hmac":     hmac,
+	}
+	for name, token := range cases {
+		if _, err := provider.Verify(ctx, token, "n"); err == nil {
+			t.Errorf("%s: invalid id token should be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/puras/mog/errors"
+	"github.com/puras/mog/jwtx"
+)
+
+// ErrInvalidIdToken ID Token 签名、签发方、受众、有效期或 nonce 校验失败
+var ErrInvalidIdToken = errors.Unauthorized("invalid_id_token", "Invalid ID token")
+
+// Config OIDC 提供方及本应用（客户端）的配置
+type Config struct {
+	Issuer       string // 提供方地址，用于发现 /.well-known/openid-configuration
+	ClientId     string
+	ClientSecret string
+	RedirectURL  string   // 回调地址，需在提供方登记
+	Scopes       []string // 默认为 openid profile email
+	Leeway       time.Duration
+	HTTPClient   *http.Client
+}
+
+// Discovery 提供方的 OpenID Connect 发现文档
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint,omitempty"`
+}
+
+// IdToken 校验通过的 ID Token 声明，Raw 为完整的声明，可用于映射本地用户
+type IdToken struct {
+	Issuer        string         `json:"iss"`
+	Subject       string         `json:"sub"`
+	Email         string         `json:"email"`
+	EmailVerified bool           `json:"email_verified"`
+	Name          string         `json:"name"`
+	Nonce         string         `json:"nonce"`
+	Raw           map[string]any `json:"-"`
+}
+
+// Provider 完成发现并缓存提供方 JWKS 的 OIDC 提供方
+type Provider struct {
+	cfg       Config
+	discovery Discovery
+
+	mu        sync.Mutex
+	keys      map[string]jwtx.JSONWebKey
+	fetchedAt time.Time
+}
+
+// minRefreshInterval 遇到未知 kid 时两次获取 JWKS 的最小间隔，避免伪造的 kid 频繁触发请求
+const minRefreshInterval = time.Minute
+
+// NewProvider 读取提供方的发现文档，签发方必须与配置一致
+func NewProvider(ctx context.Context, cfg Config) (*Provider, error) {
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	}
+	p := &Provider{cfg: cfg}
+	url := strings.TrimSuffix(cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, url, &p.discovery); err != nil {
+		return nil, err
+	}
+	if p.discovery.Issuer != cfg.Issuer {
+		return nil, fmt.Errorf("oidc: issuer mismatch, expected %s, got %s", cfg.Issuer, p.discovery.Issuer)
+	}
+	return p, nil
+}
+
+func (p *Provider) Discovery() Discovery {
+	return p.discovery
+}
+
+// Verify 校验 ID Token 的签名（提供方 JWKS）、iss、aud、exp 及 nonce
+func (p *Provider) Verify(ctx context.Context, rawIdToken, nonce string) (*IdToken, error) {
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithIssuer(p.discovery.Issuer),
+		jwt.WithAudience(p.cfg.ClientId),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(p.cfg.Leeway),
+	)
+	_, err := parser.ParseWithClaims(rawIdToken, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.publicKey(ctx, kid, t.Method.Alg())
+	})
+	if err != nil {
+		return nil, ErrInvalidIdToken
+	}
+
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	token := &IdToken{Raw: claims}
+	if err := json.Unmarshal(b, token); err != nil {
+		return nil, ErrInvalidIdToken
+	}
+	if token.Nonce != nonce || token.Subject == "" {
+		return nil, ErrInvalidIdToken
+	}
+	// 有多个受众时 azp 必须是本应用
+	if aud, _ := claims.GetAudience(); len(aud) > 1 {
+		if azp, _ := claims["azp"].(string); azp != p.cfg.ClientId {
+			return nil, ErrInvalidIdToken
+		}
+	}
+	return token, nil
+}
+
+// publicKey 按 kid 查找验签公钥，找不到时重新获取 JWKS（提供方可能已轮换密钥）
+func (p *Provider) publicKey(ctx context.Context, kid, alg string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key, ok := p.lookup(kid)
+	if !ok && time.Since(p.fetchedAt) >= minRefreshInterval {
+		var set jwtx.JSONWebKeySet
+		if err := p.getJSON(ctx, p.discovery.JWKSURI, &set); err != nil {
+			return nil, err
+		}
+		p.keys, p.fetchedAt = make(map[string]jwtx.JSONWebKey, len(set.Keys)), time.Now()
+		for _, k := range set.Keys {
+			if k.Use == "" || k.Use == "sig" {
+				p.keys[k.Kid] = k
+			}
+		}
+		key, ok = p.lookup(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("oidc: unknown key %q", kid)
+	}
+	if key.Alg != "" && key.Alg != alg {
+		return nil, fmt.Errorf("oidc: key %q does not use %s", kid, alg)
+	}
+	return key.PublicKey()
+}
+
+// lookup 没有 kid 时只有一个密钥才能确定
+func (p *Provider) lookup(kid string) (jwtx.JSONWebKey, bool) {
+	if kid != "" {
+		key, ok := p.keys[kid]
+		return key, ok
+	}
+	if len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	return jwtx.JSONWebKey{}, false
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	return p.do(req, v)
+}
+
+func (p *Provider) do(req *http.Request, v any) error {
+	resp, err := p.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return errors.RemoteCallError("", "oidc: %s", err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		return errors.RemoteCallError("", "oidc: %s returned %d %s %s", req.URL.Path, resp.StatusCode, e.Error, e.Description)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
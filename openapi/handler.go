@@ -0,0 +1,67 @@
+package openapi
+
+import (
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed swagger.html
+var swaggerHTML string
+
+var swaggerTemplate = template.Must(template.New("swagger").Parse(swaggerHTML))
+
+// SwaggerUIVersion 内置的 swagger-ui-dist 版本（Apache-2.0，见 swagger-ui/LICENSE）
+const SwaggerUIVersion = "5.18.2"
+
+//go:embed swagger-ui
+var swaggerAssets embed.FS
+
+// Handler 输出 JSON 格式的文档
+func (r *Registry) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, r.Document())
+	}
+}
+
+// AssetsHandler 输出内置的 Swagger UI 静态资源
+func AssetsHandler() http.Handler {
+	sub, err := fs.Sub(swaggerAssets, "swagger-ui")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServer(http.FS(sub))
+}
+
+// UIHandler 输出 Swagger UI 页面，specURL 为文档地址，assetsURL 为静态资源地址
+func (r *Registry) UIHandler(specURL, assetsURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.Status(http.StatusOK)
+		_ = swaggerTemplate.Execute(c.Writer, map[string]string{
+			"Title":     r.Info.Title,
+			"AssetsURL": strings.TrimSuffix(assetsURL, "/"),
+			"SpecURL":   specURL,
+		})
+	}
+}
+
+// Mount 在 prefix 下挂载文档与 Swagger UI：
+//
+//	GET prefix              Swagger UI
+//	GET prefix/openapi.json 文档
+//	GET prefix/assets/*     内置的 Swagger UI 静态资源
+func (r *Registry) Mount(group gin.IRouter, prefix string) {
+	prefix = "/" + strings.Trim(prefix, "/")
+	base := ""
+	if g, ok := group.(interface{ BasePath() string }); ok {
+		base = strings.TrimSuffix(g.BasePath(), "/")
+	}
+	group.GET(prefix+"/openapi.json", r.Handler())
+	group.GET(prefix+"/assets/*filepath", gin.WrapH(http.StripPrefix(base+prefix+"/assets", AssetsHandler())))
+	group.GET(prefix, r.UIHandler(base+prefix+"/openapi.json", base+prefix+"/assets"))
+}
@@ -0,0 +1,302 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/puras/mog/web"
+)
+
+const Version = "3.0.3"
+
+// Document OpenAPI 3 文档，只包含 mog 用到的部分
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]*PathItem  `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+	Tags       []Tag                 `json:"tags,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type PathItem map[string]*Operation // key 为小写的 HTTP 方法
+
+type Operation struct {
+	Tags        []string               `json:"tags,omitempty"`
+	Summary     string                 `json:"summary,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  []*Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody           `json:"requestBody,omitempty"`
+	Responses   map[string]*Response   `json:"responses"`
+	Security    *[]map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Headers     map[string]*Header    `json:"headers,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+}
+
+// Route 手动描述的接口，Query/Body/Response 传入对应类型的零值（如 UserForm{}、[]User{}），nil 表示没有
+type Route struct {
+	Method      string
+	Path        string // 支持 gin 风格的 :id、*path 参数
+	Summary     string
+	Description string
+	Tags        []string
+	Query       any               // 查询参数结构体，取带 form tag 的字段
+	Body        any               // 请求体
+	BodyTypes   []string          // 请求体的 Content-Type，默认 application/json
+	Response    any               // 成功响应 ResponseResult.data 的类型
+	Page        bool              // data 为 PageResult，Response 为列表元素的类型
+	Produces    []string          // 成功时返回文件（如导出）的 Content-Type，不再使用 ResponseResult 包装
+	Headers     map[string]string // 请求头参数，value 为说明
+	ResHeaders  map[string]string // 成功响应的响应头，value 为说明
+	Public      bool              // 不需要认证
+}
+
+// Registry 收集接口描述并生成文档，可并发使用
+type Registry struct {
+	mu     sync.RWMutex
+	Info   Info
+	routes []Route
+}
+
+// Default 默认注册表，crud.Register 会自动把资源路由登记到这里
+var Default = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{Info: Info{Title: "API", Version: "1.0.0"}}
+}
+
+// Add 登记路由，Method 和 Path 相同的路由只保留最后一次登记的描述
+func (r *Registry) Add(routes ...Route) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, route := range routes {
+		i := slices.IndexFunc(r.routes, func(v Route) bool {
+			return strings.EqualFold(v.Method, route.Method) && v.Path == route.Path
+		})
+		if i >= 0 {
+			r.routes[i] = route
+			continue
+		}
+		r.routes = append(r.routes, route)
+	}
+}
+
+func Add(routes ...Route) {
+	Default.Add(routes...)
+}
+
+const bearerAuth = "bearerAuth"
+
+// Document 根据已登记的接口生成文档
+func (r *Registry) Document() *Document {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	g := newGenerator()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    r.Info,
+		Paths:   map[string]*PathItem{},
+		Components: Components{
+			SecuritySchemes: map[string]*SecurityScheme{
+				bearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+		Security: []map[string][]string{{bearerAuth: {}}},
+	}
+
+	tags := map[string]bool{}
+	for _, route := range r.routes {
+		path, params := convertPath(route.Path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		op := g.operation(route, params)
+		(*item)[strings.ToLower(route.Method)] = op
+		for _, tag := range route.Tags {
+			tags[tag] = true
+		}
+	}
+	for tag := range tags {
+		doc.Tags = append(doc.Tags, Tag{Name: tag})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+	doc.Components.Schemas = g.schemas
+	return doc
+}
+
+var pathParamRegexp = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// convertPath 把 gin 的 /users/:id 转换为 /users/{id}
+func convertPath(path string) (string, []string) {
+	var params []string
+	path = pathParamRegexp.ReplaceAllStringFunc(path, func(s string) string {
+		params = append(params, s[1:])
+		return "{" + s[1:] + "}"
+	})
+	return path, params
+}
+
+// errorResponses 统一的错误响应，与 web.ResError 一致：errors.Error 以 ResponseResult 返回，
+// code 为 HTTP 状态码，message 为 errors.Error 的 detail
+var errorResponses = map[string]string{
+	"400": http.StatusText(http.StatusBadRequest),
+	"401": http.StatusText(http.StatusUnauthorized),
+	"403": http.StatusText(http.StatusForbidden),
+	"404": http.StatusText(http.StatusNotFound),
+	"409": http.StatusText(http.StatusConflict),
+	"500": http.StatusText(http.StatusInternalServerError),
+}
+
+func (g *generator) operation(route Route, pathParams []string) *Operation {
+	op := &Operation{
+		Tags:        route.Tags,
+		Summary:     route.Summary,
+		Description: route.Description,
+		Responses:   map[string]*Response{},
+	}
+	if route.Public {
+		op.Security = &[]map[string][]string{}
+	}
+
+	for _, name := range pathParams {
+		op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	names := make([]string, 0, len(route.Headers))
+	for name := range route.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "header", Description: route.Headers[name], Schema: &Schema{Type: "string"}})
+	}
+	if route.Query != nil {
+		op.Parameters = append(op.Parameters, g.queryParameters(reflect.TypeOf(route.Query))...)
+	}
+
+	if route.Body != nil {
+		body := g.schemaOf(reflect.TypeOf(route.Body))
+		types := route.BodyTypes
+		if len(types) == 0 {
+			types = []string{"application/json"}
+		}
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]*MediaType{}}
+		for _, t := range types {
+			op.RequestBody.Content[t] = &MediaType{Schema: body}
+		}
+	}
+
+	data := (*Schema)(nil)
+	if route.Response != nil {
+		data = g.schemaOf(reflect.TypeOf(route.Response))
+		if route.Page {
+			page := g.schemaOf(reflect.TypeOf(web.PageResult{}))
+			data = &Schema{AllOf: []*Schema{page, {
+				Type:       "object",
+				Properties: map[string]*Schema{"items": {Type: "array", Items: data}},
+			}}}
+		}
+	}
+	op.Responses["200"] = &Response{
+		Description: http.StatusText(http.StatusOK),
+		Content:     map[string]*MediaType{"application/json": {Schema: g.envelope(data)}},
+	}
+	if len(route.Produces) > 0 {
+		op.Responses["200"].Content = map[string]*MediaType{}
+		for _, t := range route.Produces {
+			op.Responses["200"].Content[t] = &MediaType{Schema: &Schema{Type: "string", Format: "binary"}}
+		}
+	}
+	for name, desc := range route.ResHeaders {
+		if op.Responses["200"].Headers == nil {
+			op.Responses["200"].Headers = map[string]*Header{}
+		}
+		op.Responses["200"].Headers[name] = &Header{Description: desc, Schema: &Schema{Type: "string"}}
+	}
+
+	errSchema := g.schemaOf(reflect.TypeOf(web.ResponseResult{}))
+	codes := make([]string, 0, len(errorResponses))
+	for code := range errorResponses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if code == "401" && route.Public {
+			continue
+		}
+		op.Responses[code] = &Response{
+			Description: errorResponses[code] + "，message 为 errors.Error 的 detail",
+			Content:     map[string]*MediaType{"application/json": {Schema: errSchema}},
+		}
+	}
+	return op
+}
+
+// envelope 用 web.ResponseResult 包装 data
+func (g *generator) envelope(data *Schema) *Schema {
+	result := g.schemaOf(reflect.TypeOf(web.ResponseResult{}))
+	if data == nil {
+		return result
+	}
+	return &Schema{AllOf: []*Schema{result, {
+		Type:       "object",
+		Properties: map[string]*Schema{"data": data},
+	}}}
+}
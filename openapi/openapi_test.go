@@ -0,0 +1,144 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testBase struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type testUser struct {
+	testBase
+	Name     string    `json:"name" description:"用户名"`
+	Password string    `json:"-"`
+	Tags     []string  `json:"tags,omitempty"`
+	Parent   *testUser `json:"parent,omitempty"`
+	Extra    map[string]int
+}
+
+type testForm struct {
+	Name string `json:"name" binding:"required"`
+	Age  int32  `json:"age"`
+}
+
+type testQuery struct {
+	Paging
+	Name string `form:"name"`
+	Skip string `form:"-"`
+}
+
+type Paging struct {
+	PageNum int `form:"page_num"`
+}
+
+func TestRegistry_Document(t *testing.T) {
+	r := NewRegistry()
+	r.Add(
+		Route{Method: http.MethodGet, Path: "/api/users", Tags: []string{"users"}, Query: testQuery{}, Response: testUser{}, Page: true},
+		Route{Method: http.MethodPost, Path: "/api/users", Tags: []string{"users"}, Body: testForm{}, Response: testUser{}},
+		Route{Method: http.MethodGet, Path: "/api/users/:id", Response: testUser{}, Public: true},
+	)
+	doc := r.Document()
+
+	get := (*doc.Paths["/api/users/{id}"])["get"]
+	if get == nil || get.Parameters[0].Name != "id" || get.Parameters[0].In != "path" {
+		t.Fatalf("path parameter not converted: %+v", doc.Paths)
+	}
+	if get.Security == nil || len(*get.Security) != 0 || get.Responses["401"] != nil {
+		t.Fatal("public route should not require auth")
+	}
+
+	query := (*doc.Paths["/api/users"])["get"]
+	if len(query.Parameters) != 2 || query.Parameters[0].Name != "page_num" || query.Parameters[1].Name != "name" {
+		t.Fatalf("unexpected query parameters: %+v", query.Parameters)
+	}
+
+	user := doc.Components.Schemas["testUser"]
+	if user == nil {
+		t.Fatalf("missing testUser schema: %v", doc.Components.Schemas)
+	}
+	for _, name := range []string{"id", "createdAt", "name", "tags", "parent", "Extra"} {
+		if user.Properties[name] == nil {
+			t.Errorf("missing property %s", name)
+		}
+	}
+	if user.Properties["Password"] != nil || user.Properties["password"] != nil {
+		t.Error("json:\"-\" field should be skipped")
+	}
+	if user.Properties["createdAt"].Format != "date-time" || user.Properties["parent"].Ref != "#/components/schemas/testUser" {
+		t.Errorf("unexpected property schemas: %+v", user.Properties)
+	}
+	if user.Properties["name"].Description != "用户名" {
+		t.Error("description tag should be used")
+	}
+
+	form := doc.Components.Schemas["testForm"]
+	if len(form.Required) != 1 || form.Required[0] != "name" || form.Properties["age"].Format != "int32" {
+		t.Fatalf("unexpected form schema: %+v", form)
+	}
+	if doc.Components.Schemas["ResponseResult"] == nil || doc.Components.Schemas["PageResult"] == nil {
+		t.Fatal("envelope schemas should be described")
+	}
+}
+
+func TestRegistry_AddDedupe(t *testing.T) {
+	r := NewRegistry()
+	r.Add(Route{Method: http.MethodGet, Path: "/ping", Summary: "old"}, Route{Method: http.MethodPost, Path: "/ping"})
+	r.Add(Route{Method: http.MethodGet, Path: "/ping", Summary: "new"})
+	if len(r.routes) != 2 {
+		t.Fatalf("routes with the same method and path should be registered once: %+v", r.routes)
+	}
+	if get := (*r.Document().Paths["/ping"])["get"]; get == nil || get.Summary != "new" {
+		t.Fatalf("the latest description should win: %+v", get)
+	}
+}
+
+func TestSchemaName(t *testing.T) {
+	type item[T any] struct{ Data T }
+	name := schemaName(reflect.TypeOf(item[testForm]{}))
+	if strings.ContainsAny(name, "[]./") {
+		t.Fatalf("schema name should be sanitized: %s", name)
+	}
+}
+
+func TestRegistry_Mount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := NewRegistry()
+	r.Add(Route{Method: http.MethodGet, Path: "/ping"})
+	e := gin.New()
+	r.Mount(e.Group("/api"), "/swagger")
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/swagger/openapi.json", nil))
+	var doc Document
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil || doc.OpenAPI != Version || doc.Paths["/ping"] == nil {
+		t.Fatalf("unexpected document: %v %s", err, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/swagger", nil))
+	if !strings.Contains(w.Body.String(), `url: "\/api\/swagger\/openapi.json"`) {
+		t.Fatalf("swagger ui should point to the document: %s", w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `src="/api/swagger/assets/swagger-ui-bundle.js"`) || strings.Contains(w.Body.String(), "persistAuthorization") {
+		t.Fatalf("swagger ui should load the embedded assets: %s", w.Body.String())
+	}
+
+	for _, name := range []string{"swagger-ui-bundle.js", "swagger-ui.css"} {
+		w = httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/swagger/assets/"+name, nil))
+		if w.Code != http.StatusOK || w.Body.Len() == 0 {
+			t.Fatalf("asset %s should be served, got %d", name, w.Code)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/puras/mog/dbx"
+	"github.com/puras/mog/web"
+	"gorm.io/gorm"
+)
+
+const healthCheckTimeout = 3 * time.Second
+
+// registerHealthRoutes 注册健康检查路由：
+//   - /health 存活检查，只检查主库，主库不可用时返回 503，从库故障不影响存活；
+//   - /ready  就绪检查，附带主库及所有 resolver 连接池的状态与统计，任一连接池不可用时返回 503。
+func registerHealthRoutes(e *gin.Engine, db *gorm.DB) {
+	e.GET("/health", func(c *gin.Context) {
+		if db == nil {
+			web.ResOk(c)
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+		defer cancel()
+		if err := dbx.PingPrimary(ctx, db); err != nil {
+			web.ResJson(c, http.StatusServiceUnavailable,
+				web.NewResponseResult(strconv.Itoa(http.StatusServiceUnavailable), "Database unavailable", nil))
+			return
+		}
+		web.ResOk(c)
+	})
+
+	e.GET("/ready", func(c *gin.Context) {
+		if db == nil {
+			web.ResOk(c)
+			return
+		}
+		statuses, ok := pingDB(c, db)
+		if !ok {
+			web.ResJson(c, http.StatusServiceUnavailable,
+				web.NewResponseResult(strconv.Itoa(http.StatusServiceUnavailable), "Database unavailable", statuses))
+			return
+		}
+		web.ResSuccess(c, statuses)
+	})
+}
+
+func pingDB(c *gin.Context, db *gorm.DB) ([]dbx.PoolStatus, bool) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+	defer cancel()
+	return dbx.Ping(ctx, db)
+}
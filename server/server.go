@@ -6,12 +6,14 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
 	"github.com/puras/mog/config"
 	"github.com/puras/mog/errors"
 	"github.com/puras/mog/inject"
+	"github.com/puras/mog/jwtx"
 	"github.com/puras/mog/logger"
 	"github.com/puras/mog/middleware"
 	"github.com/puras/mog/web"
@@ -19,6 +21,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 func Run(ctx context.Context, handler func(ctx context.Context) (func(), error)) error {
@@ -54,7 +57,14 @@ EXIT:
 func Start(ctx context.Context, injector *inject.Injector, registryRoutes func(ctx context.Context, e *gin.Engine) error, parseCurrentUser func(c *gin.Context) (*middleware.AuthInfo, error)) (func(), error) {
 	logger.From(ctx).Info("Start...")
 
-	clean, err := startHTTPServer(ctx, registryRoutes, parseCurrentUser)
+	var (
+		db   *gorm.DB
+		auth jwtx.Auth
+	)
+	if injector != nil {
+		db, auth = injector.DB, injector.Auth
+	}
+	clean, err := startHTTPServer(ctx, db, auth, registryRoutes, parseCurrentUser)
 	if err != nil {
 		return nil, err
 	}
@@ -66,7 +76,7 @@ func Start(ctx context.Context, injector *inject.Injector, registryRoutes func(c
 	}, nil
 }
 
-func startHTTPServer(ctx context.Context, registryRoutes func(ctx context.Context, e *gin.Engine) error, parseCurrentUser func(c *gin.Context) (*middleware.AuthInfo, error)) (func(), error) {
+func startHTTPServer(ctx context.Context, db *gorm.DB, auth jwtx.Auth, registryRoutes func(ctx context.Context, e *gin.Engine) error, parseCurrentUser func(c *gin.Context) (*middleware.AuthInfo, error)) (func(), error) {
 	gin.SetMode(gin.DebugMode)
 
 	e := gin.New()
@@ -75,15 +85,46 @@ func startHTTPServer(ctx context.Context, registryRoutes func(ctx context.Contex
 	e.Use(middleware.Recovery())
 	e.Use(middleware.Trace())
 	e.Use(middleware.Logger())
+	e.Use(middleware.StickyPrimaryWithConfig(middleware.StickyPrimaryConfig{
+		Window: time.Second * time.Duration(config.C.Storage.DataBase.StickyPrimary),
+	}))
+	// 复制一份，避免 append 写入配置的底层数组
+	skippedPathPrefixes := slices.Clone(config.C.Middleware.Auth.SkippedPathPrefixes)
+	if config.C.General.EnableSwagger {
+		skippedPathPrefixes = append(skippedPathPrefixes, swaggerPath())
+	}
+	keySet, hasKeySet := auth.(jwtx.KeySet)
+	if hasKeySet {
+		skippedPathPrefixes = append(skippedPathPrefixes, jwtx.JWKSPath)
+	}
+	introspectClients := make(map[string]string)
+	for _, client := range config.C.Middleware.Auth.IntrospectClients {
+		introspectClients[client.ClientId] = client.ClientSecret
+	}
+	mountIntrospect := auth != nil && len(introspectClients) > 0
+	if mountIntrospect {
+		// 自省接口自行校验客户端凭证，不走用户令牌认证
+		skippedPathPrefixes = append(skippedPathPrefixes, jwtx.IntrospectPath)
+	}
+	if parseCurrentUser == nil && auth != nil {
+		parseCurrentUser = middleware.JWTParser(auth)
+	}
 	e.Use(middleware.AuthWithConfig(middleware.AuthConfig{
 		AllowedPathPrefixes: []string{config.C.General.ContextPath},
-		SkippedPathPrefixes: config.C.Middleware.Auth.SkippedPathPrefixes,
+		SkippedPathPrefixes: skippedPathPrefixes,
 		Parse:               parseCurrentUser,
 	}))
 
-	e.GET("/health", func(c *gin.Context) {
-		web.ResOk(c)
-	})
+	registerHealthRoutes(e, db)
+	if config.C.General.EnableSwagger {
+		registerSwaggerRoutes(e)
+	}
+	if hasKeySet {
+		e.GET(jwtx.JWKSPath, gin.WrapF(jwtx.JWKSHandler(keySet)))
+	}
+	if mountIntrospect {
+		e.POST(jwtx.IntrospectPath, gin.WrapF(jwtx.IntrospectHandler(auth, introspectClients)))
+	}
 
 	e.NoMethod(func(c *gin.Context) {
 		web.ResError(c, errors.MethodNotAllowed("", "Method not allowed"))
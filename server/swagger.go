@@ -0,0 +1,20 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/puras/mog/config"
+	"github.com/puras/mog/openapi"
+)
+
+// swaggerPath 文档与 Swagger UI 的路径，位于 ContextPath 之下
+func swaggerPath() string {
+	return strings.TrimSuffix(config.C.General.ContextPath, "/") + "/swagger"
+}
+
+// registerSwaggerRoutes 挂载 openapi.Default 的文档（swaggerPath()/openapi.json）和 Swagger UI（swaggerPath()）
+func registerSwaggerRoutes(e *gin.Engine) {
+	openapi.Default.Info.Title = config.C.General.AppName
+	openapi.Default.Mount(e, swaggerPath())
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/puras/mog/dbx"
 	"github.com/puras/mog/errors"
+	"github.com/puras/mog/i18n"
 	"github.com/puras/mog/logger"
 
 	"github.com/gin-gonic/gin"
@@ -112,7 +113,7 @@ func ResError(c *gin.Context, err error, status ...int) {
 	if e, ok := errors.As(err); ok {
 		er = e
 	} else {
-		er = errors.FromError(errors.InternalServerError("", err.Error()))
+		er = errors.FromError(errors.InternalServerError("", "%s", err.Error()))
 	}
 
 	code := int(er.Code)
@@ -147,7 +148,7 @@ func ResError(c *gin.Context, err error, status ...int) {
 	// }
 
 	er.Code = int32(code)
-	ResJson(c, code, NewResponseResult(strconv.Itoa(code), er.Detail, nil))
+	ResJson(c, code, NewResponseResult(strconv.Itoa(code), i18n.Localize(Language(c), er), nil))
 }
 
 func NewResponseResult(code string, message string, data any) ResponseResult {
@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/puras/mog/i18n"
 )
 
 const PARAM_ID = "id"
@@ -25,3 +26,8 @@ func GetToken(c *gin.Context) string {
 
 	return token
 }
+
+// Language 按请求的 Accept-Language 选择消息语言，不支持时返回空字符串（使用默认语言）
+func Language(c *gin.Context) string {
+	return i18n.Match(c.GetHeader("Accept-Language"))
+}